@@ -0,0 +1,174 @@
+package bitradix
+
+import "slices"
+
+// Aggregate returns a new tree with the minimal number of prefixes that covers
+// the same addresses with the same values as r: a lookup of any address finds
+// the same value in both trees. Sibling prefixes with equal values merge into
+// their parent, more-specifics with the value of a covering prefix disappear, and
+// the value of a covering prefix may change when that saves prefixes. As
+// 0.0.0.0/0 (and ::/0) can not be stored, the two halves of the address space
+// are never merged. Aggregate panics with ErrNotRoot when r is not the root of a tree.
+//
+// The minimisation is the optimal routing table construction (ORTC) of Draves et al.
+func Aggregate[K Key, V comparable](r *Radix[K, V]) *Radix[K, V] {
+	if r.parent != nil {
+		panic(ErrNotRoot)
+	}
+	a := aggregation[K, V]{costs: make(map[*Radix[K, V]]aggCost[V])}
+	var v V
+	a.cost(r, v, false)
+	a.emit(r, v, false, v, false)
+	slices.SortFunc(a.prefixes, func(x, y aggregatedPrefix[K, V]) int { return comparePrefix(x.key, x.bits, y.key, y.bits) })
+	s := &sorted[K, V]{}
+	for _, p := range a.prefixes {
+		s.add(p.key, p.bits, p.value)
+	}
+	return s.build()
+}
+
+// aggCost is the minimal number of prefixes needed inside a part of the address
+// space. When the part is fully covered, m prefixes are needed when the longest
+// prefix above it has one of the values in best and m+1 otherwise. When some
+// addresses are not covered, no prefix can be above it and m are needed.
+type aggCost[V comparable] struct {
+	m     int
+	best  []V
+	holes bool
+}
+
+// none returns the number of prefixes needed without a prefix above.
+func (c aggCost[V]) none() int {
+	if c.holes {
+		return c.m
+	}
+	return c.m + 1
+}
+
+// leafCost returns the cost of a part that has the value v, or no value when ok is false.
+func leafCost[V comparable](v V, ok bool) aggCost[V] {
+	if !ok {
+		return aggCost[V]{holes: true}
+	}
+	return aggCost[V]{best: []V{v}}
+}
+
+// join returns the cost of a part from the costs of its halves.
+func join[V comparable](c0, c1 aggCost[V]) aggCost[V] {
+	if c0.holes || c1.holes {
+		return aggCost[V]{m: c0.none() + c1.none(), holes: true}
+	}
+	in1 := func(v V) bool { return slices.Contains(c1.best, v) }
+	if len(c0.best) > aggSmall && len(c1.best) > aggSmall {
+		set := make(map[V]bool, len(c1.best))
+		for _, v := range c1.best {
+			set[v] = true
+		}
+		in1 = func(v V) bool { return set[v] }
+	}
+	var both []V
+	for _, v := range c0.best {
+		if in1(v) {
+			both = append(both, v)
+		}
+	}
+	if both != nil {
+		return aggCost[V]{m: c0.m + c1.m, best: both}
+	}
+	// The best values of the halves are disjoint.
+	return aggCost[V]{m: c0.m + c1.m + 1, best: append(slices.Clone(c0.best), c1.best...)}
+}
+
+// aggSmall is the number of best values up to which join does not use a map.
+const aggSmall = 8
+
+// lift returns the cost of the part j bits above a part with cost c, where the
+// other halves on the way have the value v, or no value when ok is false.
+func lift[V comparable](c aggCost[V], j int, v V, ok bool) aggCost[V] {
+	switch {
+	case j == 0:
+		return c
+	case !ok:
+		return aggCost[V]{m: c.none(), holes: true}
+	case slices.Contains(c.best, v):
+		return aggCost[V]{m: c.m, best: []V{v}}
+	case j == 1:
+		return aggCost[V]{m: c.m + 1, best: append(slices.Clone(c.best), v)}
+	}
+	return aggCost[V]{m: c.m + 1, best: []V{v}}
+}
+
+type aggregatedPrefix[K Key, V any] struct {
+	key   K
+	bits  int
+	value V
+}
+
+type aggregation[K Key, V comparable] struct {
+	costs    map[*Radix[K, V]]aggCost[V]
+	prefixes []aggregatedPrefix[K, V] // the result, in no particular order
+}
+
+// cost computes the cost of the part of the address space covered by x, v is
+// the value of the longest prefix containing x when ok is true.
+func (a *aggregation[K, V]) cost(x *Radix[K, V], v V, ok bool) aggCost[V] {
+	if x.bits != 0 {
+		v, ok = x.Value, true
+	}
+	var half [2]aggCost[V]
+	for i, y := range x.branch {
+		if y == nil {
+			half[i] = leafCost(v, ok)
+			continue
+		}
+		half[i] = lift(a.cost(y, v, ok), y.skip-x.skip-1, v, ok)
+	}
+	c := join(half[0], half[1])
+	if x.parent == nil {
+		// The root can not hold a prefix.
+		c = aggCost[V]{m: half[0].none() + half[1].none(), holes: true}
+	}
+	a.costs[x] = c
+	return c
+}
+
+// emit adds the prefixes for the part covered by x, given that the longest
+// prefix added above it has the value w, or that there is none when wok is
+// false. v and ok are as for cost.
+func (a *aggregation[K, V]) emit(x *Radix[K, V], v V, ok bool, w V, wok bool) {
+	if x.bits != 0 {
+		v, ok = x.Value, true
+	}
+	w, wok = a.choose(x.key, x.skip, a.costs[x], v, ok, w, wok)
+	delete(a.costs, x)
+	for i, y := range x.branch {
+		if y == nil {
+			a.choose(withBit(x.key, x.skip, i), x.skip+1, leafCost(v, ok), v, ok, w, wok)
+			continue
+		}
+		// The bits between x and y, the other halves on the way have the value v.
+		c, j, yw, ywok := a.costs[y], y.skip-x.skip-1, w, wok
+		for bits := x.skip + 1; bits < y.skip; bits++ {
+			yw, ywok = a.choose(maskKey(y.key, bits), bits, lift(c, j, v, ok), v, ok, yw, ywok)
+			other := 1 - int(bitK(y.key, bitSize[K]()-1-bits))
+			a.choose(withBit(maskKey(y.key, bits), bits, other), bits+1, leafCost(v, ok), v, ok, yw, ywok)
+			j--
+		}
+		a.emit(y, v, ok, yw, ywok)
+	}
+}
+
+// choose adds the prefix n/bits, which has cost c, when the value w of the
+// prefix above it is not one of the best values, and returns the value
+// of the longest prefix added that contains n/bits. It prefers v, the value
+// n/bits has in the original tree.
+func (a *aggregation[K, V]) choose(n K, bits int, c aggCost[V], v V, ok bool, w V, wok bool) (V, bool) {
+	if c.holes || (wok && slices.Contains(c.best, w)) {
+		return w, wok
+	}
+	if !ok || !slices.Contains(c.best, v) {
+		v = c.best[0]
+	}
+	a.prefixes = append(a.prefixes, aggregatedPrefix[K, V]{n, bits, v})
+	return v, true
+}
@@ -0,0 +1,184 @@
+package bitradix
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	r := New32Of[string]()
+	for _, p := range []struct {
+		n    uint32
+		bits int
+		v    string
+	}{
+		{0x0A000000, 9, "a"},  // 10.0.0.0/9 and 10.128.0.0/9 merge
+		{0x0A800000, 9, "a"},  //
+		{0x0A010000, 16, "a"}, // covered by 10.0.0.0/8 after the merge
+		{0x0A020000, 16, "b"},
+		{0x0A020000, 17, "c"}, // 10.2.0.0/16 is hidden by these two and
+		{0x0A028000, 17, "d"}, // takes the value of the first
+		{0xC0A80000, 24, "e"}, // 192.168.0.0/23 and then /22
+		{0xC0A80100, 24, "e"}, //
+		{0xC0A80200, 23, "e"}, //
+		{0x00000000, 1, "f"},  // the halves of the address space never merge
+		{0x80000000, 1, "f"},  //
+	} {
+		r.Insert(p.n, p.bits, p.v)
+		validate(t, r)
+	}
+	want := []string{
+		"0.0.0.0/1 f",
+		"10.0.0.0/8 a",
+		"10.2.0.0/16 c",
+		"10.2.128.0/17 d",
+		"128.0.0.0/1 f",
+		"192.168.0.0/22 e",
+	}
+	a := Aggregate(r)
+	validate(t, a)
+	if got := prefixList(a); !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
+
+func TestAggregateRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		// Few values and short prefixes, so that there is a lot to merge.
+		r := randomSetTree(t, rnd, 60, 11, 0, 3)
+		a := Aggregate(r)
+		validate(t, a)
+		for j := 0; j < 2000; j++ {
+			n := rnd.Uint32() >> 3
+			v, ok := lookup(r, n)
+			if w, aok := lookup(a, n); aok != ok || w != v {
+				t.Logf("Expected %d (%v) for %08x, got %d (%v)\n%s\n%s", v, ok, n, w, aok, r, a)
+				t.FailNow()
+			}
+		}
+		// Neither rule applies to the result.
+		for p, v := range a.All() {
+			if p.Bits > 1 {
+				if x := a.Find(p.Key, p.Bits-1); x != nil && x.Value == v {
+					t.Logf("%s with value %d is covered by a prefix with the same value\n%s", formatPrefix(p.Key, p.Bits), v, a)
+					t.FailNow()
+				}
+			}
+			if x := a.Get(withBit(maskKey(p.Key, p.Bits-1), p.Bits-1, 1-int(bitK(p.Key, 32-p.Bits))), p.Bits); p.Bits > 1 && x != nil && x.Value == v {
+				t.Logf("%s with value %d has a sibling with the same value\n%s", formatPrefix(p.Key, p.Bits), v, a)
+				t.FailNow()
+			}
+		}
+		if a2 := Aggregate(a); !sameTree(a, a2) {
+			t.Logf("Aggregate is not idempotent\n%s\n%s", a, a2)
+			t.FailNow()
+		}
+	}
+}
+
+func TestAggregateCovering(t *testing.T) {
+	// A covering prefix changes its value: 10.0.0.0/8 A with three /10s B is
+	// 10.0.0.0/8 B with 10.192.0.0/10 A.
+	r := New32Of[string]()
+	r.Insert(0x0A000000, 8, "a")
+	r.Insert(0x0A000000, 10, "b")
+	r.Insert(0x0A400000, 10, "b")
+	r.Insert(0x0A800000, 10, "b")
+	validate(t, r)
+	want := []string{"10.0.0.0/8 b", "10.192.0.0/10 a"}
+	if got := prefixList(Aggregate(r)); !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
+
+// aggPrefix is a prefix with a value for minimalAggregate.
+type aggPrefix struct {
+	key   uint8
+	bits  int
+	value int
+}
+
+// blockValues returns the value of a lookup in each of the 8 blocks of 32
+// addresses, -1 when there is none, for prefixes of at most 3 bits. A later
+// prefix replaces an equal earlier one, as with Insert.
+func blockValues(prefixes []aggPrefix) [8]int {
+	var v [8]int
+	for b := range v {
+		v[b] = -1
+		bits := -1
+		for _, p := range prefixes {
+			if p.bits >= bits && match(p.key, uint8(b<<5), p.bits) {
+				v[b], bits = p.value, p.bits
+			}
+		}
+	}
+	return v
+}
+
+// minimalAggregate returns the minimal number of prefixes giving the same
+// lookups as prefixes, which are at most 3 bits long, by trying all sets of
+// prefixes of at most 3 bits in order of size, up to max prefixes.
+func minimalAggregate(prefixes []aggPrefix, values, max int) int {
+	want := blockValues(prefixes)
+	var all []aggPrefix
+	for bits := 1; bits <= 3; bits++ {
+		for i := 0; i < 1<<bits; i++ {
+			all = append(all, aggPrefix{uint8(i << (8 - bits)), bits, 0})
+		}
+	}
+	var try func(set []aggPrefix, from, n int) bool
+	try = func(set []aggPrefix, from, n int) bool {
+		if n == 0 {
+			return blockValues(set) == want
+		}
+		for i := from; i < len(all); i++ {
+			for v := 0; v < values; v++ {
+				p := all[i]
+				p.value = v
+				if try(append(set, p), i+1, n-1) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for n := 0; n < max; n++ {
+		if try(nil, 0, n) {
+			return n
+		}
+	}
+	return max
+}
+
+func TestAggregateMinimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		r := New[uint8, int]()
+		var prefixes []aggPrefix
+		for j := rnd.Intn(8); j >= 0; j-- {
+			bits := 1 + rnd.Intn(3)
+			p := aggPrefix{maskKey(uint8(rnd.Intn(256)), bits), bits, rnd.Intn(3)}
+			r.Insert(p.key, p.bits, p.value)
+			validate(t, r)
+			prefixes = append(prefixes, p)
+		}
+		a := Aggregate(r)
+		validate(t, a)
+		var got []aggPrefix
+		for p, v := range a.All() {
+			got = append(got, aggPrefix{p.Key, p.Bits, v})
+		}
+		if blockValues(got) != blockValues(prefixes) {
+			t.Logf("Expected lookups %v, got %v\n%s\n%s", blockValues(prefixes), blockValues(got), r, a)
+			t.FailNow()
+		}
+		if m := minimalAggregate(prefixes, 3, len(got)); m != len(got) {
+			t.Logf("Expected %d prefixes, got %d\n%s\n%s", m, len(got), r, a)
+			t.FailNow()
+		}
+	}
+}
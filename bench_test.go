@@ -0,0 +1,314 @@
+package bitradix
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fullTableSize is about the size of the IPv4 default free zone.
+const fullTableSize = 900000
+
+// prefixLengths is the distribution of prefix lengths in a full table, in percent.
+var prefixLengths = map[int]int{
+	24: 58, 23: 9, 22: 11, 21: 5, 20: 5, 19: 4, 18: 2, 17: 1, 16: 3, 15: 1, 14: 1,
+}
+
+// prefixLengths64 is the distribution of prefix lengths in a full IPv6 table, in percent.
+var prefixLengths64 = map[int]int{
+	48: 45, 44: 10, 32: 10, 40: 6, 46: 5, 36: 5, 29: 4, 47: 3, 42: 3, 34: 3, 33: 3, 28: 1, 56: 1, 64: 1,
+}
+
+// fullTable returns n random, but deterministic, prefixes with the length
+// distribution of a full table.
+func fullTable(n int) (keys []uint32, bits []int) {
+	return randomTable[uint32](n, prefixLengths)
+}
+
+// randomTable returns n random, but deterministic, prefixes with the given
+// distribution of prefix lengths.
+func randomTable[K Key](n int, distribution map[int]int) (keys []K, bits []int) {
+	rnd := rand.New(rand.NewSource(1))
+	var lengths []int
+	for l, p := range distribution {
+		for i := 0; i < p; i++ {
+			lengths = append(lengths, l)
+		}
+	}
+	slices.Sort(lengths)
+	for i := 0; i < n; i++ {
+		l := lengths[rnd.Intn(len(lengths))]
+		keys = append(keys, maskKey(fromWords[K](rnd.Uint64(), rnd.Uint64()), l))
+		bits = append(bits, l)
+	}
+	return keys, bits
+}
+
+var (
+	fullTableOnce sync.Once
+	fullTree      *Radix32Of[uint32]
+	fullKeys      []uint32
+	fullBits      []int
+)
+
+func newFullTable() *Radix32Of[uint32] {
+	fullTableOnce.Do(func() {
+		fullKeys, fullBits = fullTable(fullTableSize)
+		fullTree = New32Of[uint32]()
+		for i := range fullKeys {
+			fullTree.Insert(fullKeys[i], fullBits[i], uint32(i))
+		}
+	})
+	return fullTree
+}
+
+// BenchmarkFullTableMemory reports the memory used per prefix for a full table.
+func BenchmarkFullTableMemory(b *testing.B) {
+	keys, bits := fullTable(fullTableSize)
+	var m0, m1 runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&m0)
+		r := New32Of[uint32]()
+		for j := range keys {
+			r.Insert(keys[j], bits[j], uint32(j))
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&m1)
+		b.ReportMetric(float64(m1.HeapAlloc-m0.HeapAlloc)/float64(len(keys)), "B/prefix")
+		runtime.KeepAlive(r)
+	}
+}
+
+// BenchmarkFullTableLookup reports the time per longest prefix match lookup in a
+// full table, for addresses in the routed address space.
+func BenchmarkFullTableLookup(b *testing.B) {
+	r := newFullTable()
+	rnd := rand.New(rand.NewSource(2))
+	addrs := make([]uint32, 1<<16)
+	for i := range addrs {
+		j := rnd.Intn(len(fullKeys))
+		addrs[i] = randomHost(rnd, fullKeys[j], fullBits[j])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.LongestMatch(addrs[i&(len(addrs)-1)])
+	}
+}
+
+// BenchmarkFrozenFullTableLookup is BenchmarkFullTableLookup on a Frozen32.
+func BenchmarkFrozenFullTableLookup(b *testing.B) {
+	f, err := Freeze32(newFullTable(), DefaultCodec[uint32]())
+	if err != nil {
+		b.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(2))
+	addrs := make([]uint32, 1<<16)
+	for i := range addrs {
+		j := rnd.Intn(len(fullKeys))
+		addrs[i] = randomHost(rnd, fullKeys[j], fullBits[j])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.LongestMatch(addrs[i&(len(addrs)-1)])
+	}
+}
+
+// BenchmarkFullTableInsert reports the time to build a full table with Insert.
+func BenchmarkFullTableInsert(b *testing.B) {
+	newFullTable()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := New32Of[uint32]()
+		for j := range fullKeys {
+			r.Insert(fullKeys[j], fullBits[j], uint32(j))
+		}
+	}
+}
+
+// BenchmarkFullTableBuild reports the time to build a full table with
+// BuildFromSorted, compare with BenchmarkFullTableInsert.
+func BenchmarkFullTableBuild(b *testing.B) {
+	keys, bits, values := sortedTable(fullTableSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildFromSorted(keys, bits, values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFullTableUnmarshal reports the time to restore a full table with
+// UnmarshalBinary, compare with BenchmarkFullTableInsert.
+func BenchmarkFullTableUnmarshal(b *testing.B) {
+	data, err := newFullTable().MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := New32Of[uint32]()
+		if err := r.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data))/float64(len(fullKeys)), "encoded-B/prefix")
+}
+
+// BenchmarkFullTableUnmarshal32 is like BenchmarkFullTableUnmarshal for a
+// Radix32, with interface{} values holding an uint32.
+func BenchmarkFullTableUnmarshal32(b *testing.B) {
+	newFullTable()
+	r := New32()
+	for j := range fullKeys {
+		r.Insert(fullKeys[j], fullBits[j], uint32(j))
+	}
+	data, err := r.MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := New32()
+		if err := r.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data))/float64(len(fullKeys)), "encoded-B/prefix")
+}
+
+// benchSizes are the table sizes of the Insert, Find, Remove and Do benchmarks.
+var benchSizes = []struct {
+	name string
+	n    int
+}{{"1k", 1000}, {"100k", 100000}, {"1M", 1000000}}
+
+// benchTable returns the prefixes for a benchmark with n prefixes, and a tree
+// holding them. Tables are cached, building a 1M tree takes seconds.
+func benchTable[K Key](n int) (keys []K, bits []int, r *Radix[K, int]) {
+	type table struct {
+		keys []K
+		bits []int
+		r    *Radix[K, int]
+	}
+	name := fmt.Sprintf("%d/%d", bitSize[K](), n)
+	if t, ok := benchTables[name].(table); ok {
+		return t.keys, t.bits, t.r
+	}
+	distribution := prefixLengths
+	if bitSize[K]() > 32 {
+		distribution = prefixLengths64
+	}
+	keys, bits = randomTable[K](n, distribution)
+	r = New[K, int]()
+	for i := range keys {
+		r.Insert(keys[i], bits[i], i)
+	}
+	benchTables[name] = table{keys, bits, r}
+	return keys, bits, r
+}
+
+var benchTables = map[string]interface{}{}
+
+// randomHost returns a random host address in n/bits.
+func randomHost[K Key](rnd *rand.Rand, n K, bits int) K {
+	hi, lo := words(n)
+	mhi, mlo := words(maskKey(fromWords[K](^uint64(0), ^uint64(0)), bits))
+	return fromWords[K](hi|rnd.Uint64()&^mhi, lo|rnd.Uint64()&^mlo)
+}
+
+// benchInsert reports the time and the memory allocated per prefix to build a table with Insert.
+func benchInsert[K Key](b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			keys, bits, _ := benchTable[K](size.n)
+			var m0, m1 runtime.MemStats
+			runtime.ReadMemStats(&m0)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := New[K, int]()
+				for j := range keys {
+					r.Insert(keys[j], bits[j], j)
+				}
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&m1)
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/prefix")
+			b.ReportMetric(float64(m1.TotalAlloc-m0.TotalAlloc)/float64(b.N*len(keys)), "B/prefix")
+		})
+	}
+}
+
+// benchFind reports the time per Find of a host address in the routed address space.
+func benchFind[K Key](b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			keys, bits, r := benchTable[K](size.n)
+			rnd := rand.New(rand.NewSource(2))
+			addrs := make([]K, 1<<16)
+			for i := range addrs {
+				j := rnd.Intn(len(keys))
+				addrs[i] = randomHost(rnd, keys[j], bits[j])
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Find(addrs[i&(len(addrs)-1)], bitSize[K]())
+			}
+		})
+	}
+}
+
+// benchRemove reports the time per prefix to remove all prefixes from a table.
+func benchRemove[K Key](b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			keys, bits, _ := benchTable[K](size.n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				r := New[K, int]()
+				for j := range keys {
+					r.Insert(keys[j], bits[j], j)
+				}
+				b.StartTimer()
+				for j := range keys {
+					r.Remove(keys[j], bits[j])
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/prefix")
+		})
+	}
+}
+
+// benchDo reports the time per node to visit a table with Do.
+func benchDo[K Key](b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			_, _, r := benchTable[K](size.n)
+			nodes := 0
+			r.Do(func(*Radix[K, int], int) { nodes++ })
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Do(func(*Radix[K, int], int) {})
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*nodes), "ns/node")
+		})
+	}
+}
+
+func BenchmarkInsert32(b *testing.B) { benchInsert[uint32](b) }
+func BenchmarkInsert64(b *testing.B) { benchInsert[uint64](b) }
+func BenchmarkFind32(b *testing.B)   { benchFind[uint32](b) }
+func BenchmarkFind64(b *testing.B)   { benchFind[uint64](b) }
+func BenchmarkRemove32(b *testing.B) { benchRemove[uint32](b) }
+func BenchmarkRemove64(b *testing.B) { benchRemove[uint64](b) }
+func BenchmarkDo32(b *testing.B)     { benchDo[uint32](b) }
+func BenchmarkDo64(b *testing.B)     { benchDo[uint64](b) }
@@ -0,0 +1,77 @@
+package bitradix
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+)
+
+// ErrNotSorted is returned by BuildFromSorted when the prefixes are not sorted.
+var ErrNotSorted = errors.New("bitradix: prefixes not sorted")
+
+// BuildFromSorted returns a tree holding the prefixes keys[i]/bits[i] with the
+// values values[i]. The prefixes must be sorted in the order of All: on key,
+// and on length for equal keys, without duplicates. The tree is built in one
+// pass without searching it, and the nodes are allocated in a single slab, which
+// is only freed when all nodes of the tree are. The result is the same as
+// inserting the prefixes one by one. It returns ErrNotSorted, or the errors of
+// TryInsert, with the index of the offending prefix.
+func BuildFromSorted[K Key, V any](keys []K, bits []int, values []V) (*Radix[K, V], error) {
+	if len(keys) != len(bits) || len(keys) != len(values) {
+		return nil, fmt.Errorf("bitradix: %d keys, %d lengths and %d values", len(keys), len(bits), len(values))
+	}
+	// The root, a node per prefix and at most one less node where branches split.
+	slab := make([]Radix[K, V], 2*len(keys)+1)
+	alloc := func() *Radix[K, V] {
+		x := &slab[0]
+		slab = slab[1:]
+		return x
+	}
+	r := alloc()
+	// The path from the root to the last prefix, the next prefix goes to the right
+	// of it, on the path or below it.
+	path := []*Radix[K, V]{r}
+	for i, n := range keys {
+		b := bits[i]
+		if err := checkPrefix(n, b, true); err != nil {
+			return nil, fmt.Errorf("%w: prefix %d", err, i)
+		}
+		if i > 0 && comparePrefix(keys[i-1], bits[i-1], n, b) >= 0 {
+			return nil, fmt.Errorf("%w: prefix %d", ErrNotSorted, i)
+		}
+		// Walk up to the node that contains n/b, c is the node below it on the path,
+		// which may be in the other branch.
+		var c *Radix[K, V]
+		for x := path[len(path)-1]; x.skip >= b || !match(x.key, n, x.skip); x = path[len(path)-1] {
+			c = x
+			path = path[:len(path)-1]
+		}
+		p := path[len(path)-1]
+		x := alloc()
+		x.set(n, b, values[i])
+		x.skip = b
+		if c == nil || bitK(c.key, bitSize[K]()-1-p.skip) != bitK(n, bitSize[K]()-1-p.skip) {
+			p.attach(x)
+			path = append(path, x)
+			continue
+		}
+		// n/b comes after c and does not contain it, put a node in between
+		// that covers both.
+		g := alloc()
+		g.skip = common(c.key, n, min(c.skip, b))
+		g.key = maskKey(n, g.skip)
+		p.replace(c, g)
+		g.attach(c)
+		g.attach(x)
+		path = append(path, g, x)
+	}
+	return r, nil
+}
+
+// comparePrefix compares a/abits and b/bbits in the order of All.
+func comparePrefix[K Key](a K, abits int, b K, bbits int) int {
+	if c := compareKey(a, b); c != 0 {
+		return c
+	}
+	return cmp.Compare(abits, bbits)
+}
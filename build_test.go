@@ -0,0 +1,80 @@
+package bitradix
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// sortedTable returns n sorted prefixes with the length distribution of a full table.
+func sortedTable(n int) (keys []uint32, bits []int, values []int) {
+	k, b := fullTable(n)
+	p := make([]Prefix[uint32], len(k))
+	for i := range k {
+		p[i] = Prefix[uint32]{k[i], b[i]}
+	}
+	slices.SortFunc(p, func(a, b Prefix[uint32]) int { return comparePrefix(a.Key, a.Bits, b.Key, b.Bits) })
+	p = slices.Compact(p)
+	for i, x := range p {
+		keys = append(keys, x.Key)
+		bits = append(bits, x.Bits)
+		values = append(values, i)
+	}
+	return keys, bits, values
+}
+
+func TestBuildFromSorted(t *testing.T) {
+	keys, bits, values := sortedTable(20000)
+	r, err := BuildFromSorted(keys, bits, values)
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+		return
+	}
+	validate(t, r)
+	r1 := New32Of[int]()
+	for i := range keys {
+		r1.Insert(keys[i], bits[i], values[i])
+	}
+	if !sameTree(r, r1) {
+		t.Logf("Expected the same tree as with Insert\n")
+		t.Fail()
+	}
+	// The tree can be changed like any other.
+	r.Remove(keys[0], bits[0])
+	r.Insert(0x0A000000, 8, -1)
+	validate(t, r)
+
+	r2, err := BuildFromSorted[uint32, interface{}](nil, nil, nil)
+	if err != nil || !r2.Leaf() {
+		t.Logf("Expected an empty tree, got %v\n", err)
+		t.Fail()
+	}
+	rs, err := BuildFromSorted([]uint32{0x0A000000, 0x0A000000, 0x0A140000, 0xC0A80000}, []int{8, 16, 14, 16}, []any{10, 16, 20, 192})
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	if x := rs.Find(0x0A150101, 32); x == nil || x.Value != 20 {
+		t.Logf("Expected 20, got %v\n", x)
+		t.Fail()
+	}
+
+	tests := []struct {
+		keys     []uint32
+		bits     []int
+		expected error
+	}{
+		{[]uint32{0x0A000000, 0x08000000}, []int{8, 8}, ErrNotSorted},
+		{[]uint32{0x0A000000, 0x0A000000}, []int{16, 8}, ErrNotSorted},
+		{[]uint32{0x0A000000, 0x0A000000}, []int{8, 8}, ErrNotSorted},
+		{[]uint32{0x0A000001, 0x0B000000}, []int{8, 8}, ErrHostBitsSet},
+		{[]uint32{0x0A000000, 0x0B000000}, []int{8, 33}, ErrInvalidPrefixLen},
+	}
+	for _, test := range tests {
+		if _, err := BuildFromSorted(test.keys, test.bits, []int{1, 2}); !errors.Is(err, test.expected) {
+			t.Logf("Expected %v, got %v\n", test.expected, err)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package bitradix
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Concurrent implements a radix tree that is safe for concurrent use. Readers
+// never block and never see a partially applied update: the tree is an
+// Immutable tree, a writer builds the new version and atomically swaps in the
+// new root. Writers are serialized.
+type Concurrent[K Key, V any] struct {
+	mu   sync.Mutex // protects writers against each other
+	root atomic.Pointer[Immutable[K, V]]
+}
+
+// Concurrent32 implements a concurrent radix tree with an uint32 as its key.
+type Concurrent32 = Concurrent[uint32, interface{}]
+
+// Concurrent32Of implements a concurrent radix tree with an uint32 as its key
+// and values of type V.
+type Concurrent32Of[V any] = Concurrent[uint32, V]
+
+// NewConcurrent returns an empty, initialized Concurrent tree.
+func NewConcurrent[K Key, V any]() *Concurrent[K, V] {
+	c := &Concurrent[K, V]{}
+	c.root.Store(NewImmutable[K, V]())
+	return c
+}
+
+// Insert inserts a new value v under n/bits in the tree c, possibly silently
+// overwriting an existing value. It returns the same errors as Radix.TryInsert,
+// except ErrNotRoot.
+func (c *Concurrent[K, V]) Insert(n K, bits int, v V) error {
+	if err := checkPrefix(n, bits, true); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.root.Store(c.root.Load().insert(n, bits, v))
+	return nil
+}
+
+// Remove removes n/bits from the tree c. It returns the value that was stored
+// and true, or false when n/bits was not found. A prefix that Insert would
+// reject is never found.
+func (c *Concurrent[K, V]) Remove(n K, bits int) (v V, ok bool) {
+	if checkPrefix(n, bits, true) != nil {
+		return v, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	root, x := c.root.Load().remove(n, bits, true)
+	if x == nil {
+		return v, false
+	}
+	c.root.Store(root)
+	return x.Value, true
+}
+
+// Snapshot returns the current version of the tree c. It is O(1), the
+// returned tree is not affected by later updates to c.
+func (c *Concurrent[K, V]) Snapshot() *Immutable[K, V] {
+	return c.root.Load()
+}
+
+// Find is like Radix.Find, it returns the prefix found and its value, or false
+// when nothing can be found.
+func (c *Concurrent[K, V]) Find(n K, bits int) (p Prefix[K], v V, ok bool) {
+	if x := c.Snapshot().Find(n, bits); x != nil {
+		return Prefix[K]{x.key, x.bits}, x.Value, true
+	}
+	return p, v, false
+}
+
+// Get returns the value stored under exactly n/bits.
+func (c *Concurrent[K, V]) Get(n K, bits int) (v V, ok bool) {
+	if p, x, ok := c.Find(n, bits); ok && p.Bits == bits {
+		return x, true
+	}
+	return v, false
+}
+
+// LongestMatch returns the longest prefix that contains the host address n and its value.
+func (c *Concurrent[K, V]) LongestMatch(n K) (Prefix[K], V, bool) {
+	return c.Find(n, bitSize[K]())
+}
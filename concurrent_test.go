@@ -0,0 +1,70 @@
+package bitradix
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrent(t *testing.T) {
+	c := NewConcurrent[uint32, uint32]()
+	// The /8 is always there, the /16s and /24s below it come and go.
+	c.Insert(0x0A000000, 8, 0x0A000000|8)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := uint32(i); ; n += 0x00010101 {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				n = 0x0A000000 | n&0x00FFFFFF
+				p, v, ok := c.LongestMatch(n)
+				if !ok {
+					t.Errorf("Expected a match for %s\n", uintToIP(n))
+					return
+				}
+				// The value is derived from the prefix, a half-done update would show here.
+				if v != p.Key|uint32(p.Bits) || !match(p.Key, n, p.Bits) {
+					t.Errorf("Got inconsistent %s/%d -> %x for %s\n", uintToIP(p.Key), p.Bits, v, uintToIP(n))
+					return
+				}
+			}
+		}(i)
+	}
+	for round := 0; round < 20; round++ {
+		for k := uint32(0); k < 256; k++ {
+			n16, n24 := 0x0A000000|k<<16, 0x0A000000|k<<16|k<<8
+			c.Insert(n16, 16, n16|16)
+			c.Insert(n24, 24, n24|24)
+		}
+		for k := uint32(0); k < 256; k++ {
+			n16, n24 := 0x0A000000|k<<16, 0x0A000000|k<<16|k<<8
+			if v, ok := c.Remove(n24, 24); !ok || v != n24|24 {
+				t.Errorf("Expected %x, got %x (%v)\n", n24|24, v, ok)
+			}
+			if round%2 == 0 {
+				c.Remove(n16, 16)
+			}
+		}
+		if err := c.Snapshot().Validate(); err != nil {
+			t.Errorf("Invalid tree after round %d: %s\n", round, err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if v, ok := c.Get(0x0A000000, 8); !ok || v != 0x0A000000|8 {
+		t.Errorf("Expected %x, got %x (%v)\n", 0x0A000000|8, v, ok)
+	}
+	if _, ok := c.Get(0x0A010100, 24); ok {
+		t.Errorf("Expected 10.1.1.0/24 to be removed\n")
+	}
+	if err := c.Insert(0x0A000001, 8, 0); err != ErrHostBitsSet {
+		t.Errorf("Expected %v, got %v\n", ErrHostBitsSet, err)
+	}
+}
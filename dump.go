@@ -0,0 +1,122 @@
+package bitradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The nodes of a dump are numbered in pre-order, starting with #0 for the node
+// the dump starts at. A node's parent is shown by its number. A parent that is
+// not part of the dump is shown as a pointer, so a stale parent link stands out.
+
+// String returns the tree under r as an indented tree, see Dump.
+func (r *Radix[K, V]) String() string {
+	var sb strings.Builder
+	r.Dump(&sb)
+	return sb.String()
+}
+
+// Dump writes the tree under r as an indented tree, one node per line. A line
+// shows the branch taken (0 or 1, the root has none), the node number, the prefix
+// covered by the node, the value or (empty) when the node holds no key, and the
+// number of the parent node.
+//
+//	#0 0.0.0.0/0 (empty)
+//	  0 #1 8.8.8.0/24 = 15169 (parent #0)
+//	  1 #2 10.0.0.0/8 = 10 (parent #0)
+func (r *Radix[K, V]) Dump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	ids := r.number()
+	var dump func(x *Radix[K, V], depth, branch int)
+	dump = func(x *Radix[K, V], depth, branch int) {
+		bw.WriteString(strings.Repeat("  ", depth))
+		if branch >= 0 {
+			fmt.Fprintf(bw, "%d ", branch)
+		}
+		fmt.Fprintf(bw, "#%d %s", ids[x], formatPrefix(x.key, x.skip))
+		if x.bits > 0 {
+			fmt.Fprintf(bw, " = %v", x.Value)
+		} else {
+			bw.WriteString(" (empty)")
+		}
+		if x != r || x.parent != nil {
+			fmt.Fprintf(bw, " (parent %s)", parentID(ids, x.parent))
+		}
+		bw.WriteByte('\n')
+		for i, b := range x.branch {
+			if b != nil {
+				dump(b, depth+1, i)
+			}
+		}
+	}
+	dump(r, 0, -1)
+	return bw.Flush()
+}
+
+// WriteDot writes the tree under r in the Graphviz DOT format. Nodes holding a
+// key show the prefix and the value, empty nodes are drawn dashed with the prefix
+// they cover. Branches are solid edges labeled 0 or 1, parent links are dotted
+// edges back to the parent, drawn in red when the parent is not the node the
+// branch comes from.
+func (r *Radix[K, V]) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	ids := r.number()
+	bw.WriteString("digraph bitradix {\n\tnode [shape=box];\n")
+	var dot func(x *Radix[K, V])
+	dot = func(x *Radix[K, V]) {
+		label := fmt.Sprintf("#%d %s", ids[x], formatPrefix(x.key, x.skip))
+		if x.bits > 0 {
+			label += fmt.Sprintf("\n%v", x.Value)
+			fmt.Fprintf(bw, "\tn%d [label=%q];\n", ids[x], label)
+		} else {
+			fmt.Fprintf(bw, "\tn%d [label=%q, style=dashed];\n", ids[x], label)
+		}
+		for i, b := range x.branch {
+			if b == nil {
+				continue
+			}
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"%d\"];\n", ids[x], ids[b], i)
+			switch id, ok := ids[b.parent]; {
+			case b.parent == x:
+				fmt.Fprintf(bw, "\tn%d -> n%d [style=dotted, constraint=false];\n", ids[b], ids[x])
+			case ok:
+				fmt.Fprintf(bw, "\tn%d -> n%d [style=dotted, color=red, constraint=false];\n", ids[b], id)
+			default:
+				fmt.Fprintf(bw, "\tn%d -> %q [style=dotted, color=red, constraint=false];\n", ids[b], parentID(ids, b.parent))
+			}
+			dot(b)
+		}
+	}
+	dot(r)
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// number returns the pre-order numbers of the nodes under r.
+func (r *Radix[K, V]) number() map[*Radix[K, V]]int {
+	ids := make(map[*Radix[K, V]]int)
+	var number func(x *Radix[K, V])
+	number = func(x *Radix[K, V]) {
+		ids[x] = len(ids)
+		for _, b := range x.branch {
+			if b != nil {
+				number(b)
+			}
+		}
+	}
+	number(r)
+	return ids
+}
+
+// parentID returns the number of p in a dump, or its address when p is not part of it.
+func parentID[K Key, V any](ids map[*Radix[K, V]]int, p *Radix[K, V]) string {
+	if p == nil {
+		return "nil"
+	}
+	if id, ok := ids[p]; ok {
+		return fmt.Sprintf("#%d", id)
+	}
+	return fmt.Sprintf("%p", p)
+}
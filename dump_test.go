@@ -0,0 +1,66 @@
+package bitradix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	r := newIterTree(t)
+	expected := `#0 0.0.0.0/0 (empty)
+  0 #1 8.0.0.0/6 (empty) (parent #0)
+    0 #2 8.8.8.0/24 = 15169 (parent #1)
+    1 #3 10.0.0.0/8 = 10 (parent #1)
+      0 #4 10.20.0.0/14 = 20 (parent #3)
+        0 #5 10.21.0.0/16 = 21 (parent #4)
+  1 #6 192.168.0.0/16 = 192 (parent #0)
+    0 #7 192.168.2.0/24 = 1922 (parent #6)
+`
+	if s := r.String(); s != expected {
+		t.Logf("Expected\n%s, got\n%s\n", expected, s)
+		t.Fail()
+	}
+
+	// A stale parent link shows up as a pointer.
+	x := r.Get(0x0a150000, 16)
+	x.parent = &Radix32{}
+	if s := r.String(); !strings.Contains(s, "10.21.0.0/16 = 21 (parent 0x") {
+		t.Logf("Expected a pointer as parent of 10.21.0.0/16, got\n%s\n", s)
+		t.Fail()
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	r := newIterTree(t)
+	var sb strings.Builder
+	if err := r.WriteDot(&sb); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	dot := sb.String()
+	t.Logf("\n%s", dot)
+	for _, s := range []string{
+		"digraph bitradix {",
+		`n1 [label="#1 8.0.0.0/6", style=dashed];`,
+		`n3 [label="#3 10.0.0.0/8\n10"];`,
+		`n3 -> n4 [label="0"];`,
+		`n4 -> n3 [style=dotted, constraint=false];`,
+	} {
+		if !strings.Contains(dot, s) {
+			t.Logf("Expected %s in the output\n", s)
+			t.Fail()
+		}
+	}
+	if strings.Contains(dot, "red") {
+		t.Logf("Expected no red parent links\n")
+		t.Fail()
+	}
+
+	r.Get(0x0a150000, 16).parent = r
+	sb.Reset()
+	r.WriteDot(&sb)
+	if !strings.Contains(sb.String(), "n5 -> n0 [style=dotted, color=red, constraint=false];") {
+		t.Logf("Expected a red parent link for 10.21.0.0/16\n")
+		t.Fail()
+	}
+}
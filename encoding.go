@@ -0,0 +1,542 @@
+package bitradix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// The binary format of a tree is a header followed by the nodes in pre-order.
+//
+//	header: "BRDX", version (1 byte), bits in the key (1 byte)
+//	node:   flags (1 byte), skip (1 byte), the first skip bits of the key in
+//	        big endian order rounded up to whole bytes, and when the node holds
+//	        a key, the length of the encoded value (uvarint) and the value.
+//
+// The flags tell if the node holds a key, and if it has a zero and a one branch.
+// Because all nodes are written, restoring a tree does not need to search it.
+const (
+	encodingMagic   = "BRDX"
+	encodingVersion = 1
+
+	flagKey     = 1 << 0
+	flagBranch0 = 1 << 1
+	flagBranch1 = 1 << 2
+)
+
+// ErrFormat is returned when decoding data that is not a valid encoded tree.
+var ErrFormat = errors.New("bitradix: invalid binary format")
+
+// Codec encodes and decodes the values stored in a tree.
+type Codec[V any] interface {
+	// AppendValue appends the encoding of v to b and returns the extended buffer.
+	AppendValue(b []byte, v V) ([]byte, error)
+	// DecodeValue decodes a value from b, which holds exactly one encoded value.
+	// b is only valid during the call, it is reused for the next value: the
+	// value returned must not refer to it.
+	DecodeValue(b []byte) (V, error)
+}
+
+// DefaultCodec returns the codec used by MarshalBinary, UnmarshalBinary, WriteTo
+// and ReadFrom. Strings and byte slices are stored as is, types implementing
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler use those methods,
+// integers are stored as varints and other fixed-size types use encoding/binary.
+// interface{} values holding nil, a bool, a number, a string or a byte slice
+// are stored as a type tag followed by the value like above. Everything else
+// uses encoding/gob, which is slow and needs the types held by interface{}
+// values to be registered with gob.Register.
+func DefaultCodec[V any]() Codec[V] {
+	t := reflect.TypeFor[V]()
+	switch {
+	case t.Kind() == reflect.String:
+		return stringCodec[V]{}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return bytesCodec[V]{}
+	case t.Implements(reflect.TypeFor[encoding.BinaryMarshaler]()) &&
+		reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.BinaryUnmarshaler]()):
+		return marshalerCodec[V]{}
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		return anyCodec[V]{}
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Uint || t.Kind() == reflect.Uintptr:
+		return varintCodec[V]{}
+	case t.Kind() != reflect.Interface && binary.Size(reflect.Zero(t).Interface()) > 0:
+		return fixedCodec[V]{}
+	}
+	return gobCodec[V]{}
+}
+
+type stringCodec[V any] struct{}
+
+func (stringCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	return append(b, reflect.ValueOf(v).String()...), nil
+}
+
+func (stringCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	reflect.ValueOf(&v).Elem().SetString(string(b))
+	return v, nil
+}
+
+type bytesCodec[V any] struct{}
+
+func (bytesCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	return append(b, reflect.ValueOf(v).Bytes()...), nil
+}
+
+func (bytesCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	reflect.ValueOf(&v).Elem().SetBytes(bytes.Clone(b))
+	return v, nil
+}
+
+type marshalerCodec[V any] struct{}
+
+func (marshalerCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	data, err := any(v).(encoding.BinaryMarshaler).MarshalBinary()
+	return append(b, data...), err
+}
+
+func (marshalerCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	err = any(&v).(encoding.BinaryUnmarshaler).UnmarshalBinary(b)
+	return v, err
+}
+
+type fixedCodec[V any] struct{}
+
+func (fixedCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	return binary.Append(b, binary.BigEndian, v)
+}
+
+func (fixedCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	_, err = binary.Decode(b, binary.BigEndian, &v)
+	return v, err
+}
+
+// varintCodec stores int, uint and uintptr types, for which encoding/binary has no size.
+type varintCodec[V any] struct{}
+
+func (varintCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	x := reflect.ValueOf(v)
+	if x.CanInt() {
+		return binary.AppendVarint(b, x.Int()), nil
+	}
+	return binary.AppendUvarint(b, x.Uint()), nil
+}
+
+func (varintCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	x := reflect.ValueOf(&v).Elem()
+	if x.CanInt() {
+		n, l := binary.Varint(b)
+		if l != len(b) || x.OverflowInt(n) {
+			return v, ErrFormat
+		}
+		x.SetInt(n)
+		return v, nil
+	}
+	n, l := binary.Uvarint(b)
+	if l != len(b) || x.OverflowUint(n) {
+		return v, ErrFormat
+	}
+	x.SetUint(n)
+	return v, nil
+}
+
+// The type tags of anyCodec.
+const (
+	tagNil = iota
+	tagBool
+	tagInt
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagUint
+	tagUint8
+	tagUint16
+	tagUint32
+	tagUint64
+	tagUintptr
+	tagFloat32
+	tagFloat64
+	tagString
+	tagBytes
+	tagGob = 0xFF
+)
+
+// anyCodec stores interface{} values as a type tag and the value, values of
+// other types than the ones with a tag use gob.
+type anyCodec[V any] struct{}
+
+func (anyCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	switch x := any(v).(type) {
+	case nil:
+		return append(b, tagNil), nil
+	case bool:
+		if x {
+			return append(b, tagBool, 1), nil
+		}
+		return append(b, tagBool, 0), nil
+	case int:
+		return binary.AppendVarint(append(b, tagInt), int64(x)), nil
+	case int8:
+		return binary.AppendVarint(append(b, tagInt8), int64(x)), nil
+	case int16:
+		return binary.AppendVarint(append(b, tagInt16), int64(x)), nil
+	case int32:
+		return binary.AppendVarint(append(b, tagInt32), int64(x)), nil
+	case int64:
+		return binary.AppendVarint(append(b, tagInt64), x), nil
+	case uint:
+		return binary.AppendUvarint(append(b, tagUint), uint64(x)), nil
+	case uint8:
+		return binary.AppendUvarint(append(b, tagUint8), uint64(x)), nil
+	case uint16:
+		return binary.AppendUvarint(append(b, tagUint16), uint64(x)), nil
+	case uint32:
+		return binary.AppendUvarint(append(b, tagUint32), uint64(x)), nil
+	case uint64:
+		return binary.AppendUvarint(append(b, tagUint64), x), nil
+	case uintptr:
+		return binary.AppendUvarint(append(b, tagUintptr), uint64(x)), nil
+	case float32:
+		return binary.BigEndian.AppendUint32(append(b, tagFloat32), math.Float32bits(x)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, tagFloat64), math.Float64bits(x)), nil
+	case string:
+		return append(append(b, tagString), x...), nil
+	case []byte:
+		return append(append(b, tagBytes), x...), nil
+	}
+	return gobCodec[V]{}.AppendValue(append(b, tagGob), v)
+}
+
+func (anyCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	if len(b) == 0 {
+		return v, ErrFormat
+	}
+	tag, b := b[0], b[1:]
+	if tag == tagGob {
+		return gobCodec[V]{}.DecodeValue(b)
+	}
+	var (
+		x     any
+		ok    = true
+		i, il = binary.Varint(b)
+		u, ul = binary.Uvarint(b)
+	)
+	switch tag {
+	case tagNil:
+		ok = len(b) == 0
+	case tagBool:
+		x, ok = len(b) == 1 && b[0] == 1, len(b) == 1 && b[0] <= 1
+	case tagInt:
+		x, ok = int(i), il == len(b) && int64(int(i)) == i
+	case tagInt8:
+		x, ok = int8(i), il == len(b) && int64(int8(i)) == i
+	case tagInt16:
+		x, ok = int16(i), il == len(b) && int64(int16(i)) == i
+	case tagInt32:
+		x, ok = int32(i), il == len(b) && int64(int32(i)) == i
+	case tagInt64:
+		x, ok = i, il == len(b)
+	case tagUint:
+		x, ok = uint(u), ul == len(b) && uint64(uint(u)) == u
+	case tagUint8:
+		x, ok = uint8(u), ul == len(b) && uint64(uint8(u)) == u
+	case tagUint16:
+		x, ok = uint16(u), ul == len(b) && uint64(uint16(u)) == u
+	case tagUint32:
+		x, ok = uint32(u), ul == len(b) && uint64(uint32(u)) == u
+	case tagUint64:
+		x, ok = u, ul == len(b)
+	case tagUintptr:
+		x, ok = uintptr(u), ul == len(b) && uint64(uintptr(u)) == u
+	case tagFloat32:
+		if ok = len(b) == 4; ok {
+			x = math.Float32frombits(binary.BigEndian.Uint32(b))
+		}
+	case tagFloat64:
+		if ok = len(b) == 8; ok {
+			x = math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case tagString:
+		x = string(b)
+	case tagBytes:
+		x = bytes.Clone(b)
+	default:
+		ok = false
+	}
+	if !ok {
+		return v, fmt.Errorf("%w: invalid value with type tag %d", ErrFormat, tag)
+	}
+	if x != nil {
+		v = x.(V)
+	}
+	return v, nil
+}
+
+type gobCodec[V any] struct{}
+
+func (gobCodec[V]) AppendValue(b []byte, v V) ([]byte, error) {
+	buf := bytes.NewBuffer(b)
+	err := gob.NewEncoder(buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec[V]) DecodeValue(b []byte) (v V, err error) {
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, values are encoded with
+// DefaultCodec. It returns ErrNotRoot when r is not the root of a tree.
+func (r *Radix[K, V]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	err := r.Encode(&buf, DefaultCodec[V]())
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, values are decoded with
+// DefaultCodec. The contents of r are replaced, r must be the root of a tree.
+func (r *Radix[K, V]) UnmarshalBinary(data []byte) error {
+	return r.Decode(bytes.NewReader(data), DefaultCodec[V]())
+}
+
+// WriteTo implements io.WriterTo, it writes the tree like MarshalBinary. It
+// returns ErrNotRoot when r is not the root of a tree.
+func (r *Radix[K, V]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	err := r.Encode(cw, DefaultCodec[V]())
+	return cw.n, err
+}
+
+// ReadFrom implements io.ReaderFrom, it reads a tree written by WriteTo. The
+// contents of r are replaced, r must be the root of a tree. When rd does not
+// implement io.ByteReader, data following the tree may have been read from rd.
+func (r *Radix[K, V]) ReadFrom(rd io.Reader) (int64, error) {
+	cr := &countReader{r: rd}
+	if _, ok := rd.(io.ByteReader); !ok {
+		err := r.Decode(bufio.NewReader(cr), DefaultCodec[V]())
+		return cr.n, err
+	}
+	err := r.Decode(cr, DefaultCodec[V]())
+	return cr.n, err
+}
+
+// Encode writes the tree r to w, values are encoded with c. It returns
+// ErrNotRoot when r is not the root of a tree.
+func (r *Radix[K, V]) Encode(w io.Writer, c Codec[V]) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(encodingMagic)
+	bw.WriteByte(encodingVersion)
+	bw.WriteByte(byte(bitSize[K]()))
+	var (
+		buf []byte
+		err error
+	)
+	var encode func(x *Radix[K, V]) error
+	encode = func(x *Radix[K, V]) error {
+		buf = buf[:0]
+		flags := byte(0)
+		if x.bits > 0 {
+			flags |= flagKey
+		}
+		if x.branch[0] != nil {
+			flags |= flagBranch0
+		}
+		if x.branch[1] != nil {
+			flags |= flagBranch1
+		}
+		buf = append(buf, flags, byte(x.skip))
+		buf = appendKey(buf, x.key, x.skip)
+		if x.bits > 0 {
+			// Reserve the maximum uvarint length, and move the value when it is shorter.
+			start := len(buf)
+			buf = append(buf, make([]byte, binary.MaxVarintLen64)...)
+			if buf, err = c.AppendValue(buf, x.Value); err != nil {
+				return err
+			}
+			size := len(buf) - start - binary.MaxVarintLen64
+			l := binary.PutUvarint(buf[start:], uint64(size))
+			copy(buf[start+l:], buf[start+binary.MaxVarintLen64:])
+			buf = buf[:start+l+size]
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		for _, b := range x.branch {
+			if b != nil {
+				if err := encode(b); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := encode(r); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Decode reads a tree written by Encode from rd, values are decoded with c. The
+// contents of r are replaced, r must be the root of a tree. When rd does not
+// implement io.ByteReader, data following the tree may have been read from rd.
+func (r *Radix[K, V]) Decode(rd io.Reader, c Codec[V]) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	br, ok := rd.(byteReader)
+	if !ok {
+		br = bufio.NewReader(rd)
+	}
+	header := make([]byte, len(encodingMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return err
+	}
+	if string(header[:len(encodingMagic)]) != encodingMagic {
+		return ErrFormat
+	}
+	if header[len(encodingMagic)] != encodingVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrFormat, header[len(encodingMagic)])
+	}
+	if int(header[len(encodingMagic)+1]) != bitSize[K]() {
+		return fmt.Errorf("%w: key has %d bits, not %d", ErrFormat, header[len(encodingMagic)+1], bitSize[K]())
+	}
+
+	var (
+		buf [2 + 16]byte
+		val bytes.Buffer
+	)
+	var decode func(parent *Radix[K, V]) (*Radix[K, V], error)
+	decode = func(parent *Radix[K, V]) (*Radix[K, V], error) {
+		if _, err := io.ReadFull(br, buf[:2]); err != nil {
+			return nil, noEOF(err)
+		}
+		flags, skip := buf[0], int(buf[1])
+		if skip > bitSize[K]() || (parent == nil) != (skip == 0) || (parent != nil && skip <= parent.skip) {
+			return nil, ErrFormat
+		}
+		kb := buf[2 : 2+(skip+7)/8]
+		if _, err := io.ReadFull(br, kb); err != nil {
+			return nil, noEOF(err)
+		}
+		x := &Radix[K, V]{parent: parent, key: keyFromBytes[K](kb), skip: skip}
+		if hostBits(x.key, skip) || (parent != nil && !match(x.key, parent.key, parent.skip)) {
+			return nil, ErrFormat
+		}
+		if flags&flagKey != 0 {
+			if parent == nil {
+				return nil, fmt.Errorf("%w: root holds a key", ErrFormat)
+			}
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			if size > uint64(1<<31) {
+				return nil, ErrFormat
+			}
+			// Grow the buffer while reading, the size may be bogus.
+			val.Reset()
+			if _, err := io.CopyN(&val, br, int64(size)); err != nil {
+				return nil, noEOF(err)
+			}
+			if x.Value, err = c.DecodeValue(val.Bytes()); err != nil {
+				return nil, err
+			}
+			x.bits = skip
+		}
+		for i, f := range []byte{flagBranch0, flagBranch1} {
+			if flags&f == 0 {
+				continue
+			}
+			b, err := decode(x)
+			if err != nil {
+				return nil, err
+			}
+			if int(bitK(b.key, bitSize[K]()-1-skip)) != i {
+				return nil, ErrFormat
+			}
+			x.branch[i] = b
+		}
+		if parent != nil && x.bits == 0 && (x.branch[0] == nil || x.branch[1] == nil) {
+			return nil, ErrFormat
+		}
+		return x, nil
+	}
+	x, err := decode(nil)
+	if err != nil {
+		return err
+	}
+	r.adopt(x)
+	return nil
+}
+
+// appendKey appends the first bits bits of n to b, in big endian order rounded up to whole bytes.
+func appendKey[K Key](b []byte, n K, bits int) []byte {
+	var buf [16]byte
+	hi, lo := words(n)
+	binary.BigEndian.PutUint64(buf[:8], hi)
+	binary.BigEndian.PutUint64(buf[8:], lo)
+	start := 16 - bitSize[K]()/8
+	return append(b, buf[start:start+(bits+7)/8]...)
+}
+
+// keyFromBytes is the inverse of appendKey.
+func keyFromBytes[K Key](b []byte) K {
+	var buf [16]byte
+	copy(buf[16-bitSize[K]()/8:], b)
+	return fromWords[K](binary.BigEndian.Uint64(buf[:8]), binary.BigEndian.Uint64(buf[8:]))
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, the tree is not complete.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// countReader counts the bytes read, ReadByte may only be used when r is an io.ByteReader.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	b, err := c.r.(io.ByteReader).ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
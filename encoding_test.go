@@ -0,0 +1,277 @@
+package bitradix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// sameTree reports if a and b hold the same prefixes and values in the same shape.
+func sameTree[K Key, V comparable](a, b *Radix[K, V]) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.key != b.key || a.skip != b.skip || a.bits != b.bits || a.Value != b.Value {
+		return false
+	}
+	for i := range a.branch {
+		if a.branch[i] != nil && a.branch[i].parent != a {
+			return false
+		}
+	}
+	return sameTree(a.branch[0], b.branch[0]) && sameTree(a.branch[1], b.branch[1])
+}
+
+func TestMarshalBinary(t *testing.T) {
+	r := newIterTree(t)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	t.Logf("Encoded in %d bytes\n", len(data))
+	r1 := New32()
+	if err := r1.UnmarshalBinary(data); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	validate(t, r1)
+	if !sameTree(r, r1) {
+		t.Logf("Expected the same tree after UnmarshalBinary\n")
+		t.Fail()
+	}
+	if x := findRoute(t, r1, "10.21.1.1/32"); x != uint32(21) {
+		t.Logf("Expected %d, got %d\n", 21, x)
+		t.Fail()
+	}
+
+	// Empty tree.
+	data, _ = New64().MarshalBinary()
+	r2 := New64()
+	r2.Insert(0x8000000000000000, 1, 1)
+	validate(t, r2)
+	if err := r2.UnmarshalBinary(data); err != nil || r2.branch[0] != nil || r2.branch[1] != nil {
+		t.Logf("Expected an empty tree, got %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	r := New64Of[string]()
+	r.Insert(0x2001db8000000000, 32, "doc")
+	validate(t, r)
+	r.Insert(0x2001db8000100000, 44, "")
+	validate(t, r)
+	r.Insert(0x2a00145040010800, 56, "google")
+	validate(t, r)
+	r2 := New64Of[string]()
+	r2.Insert(0xfe80000000000000, 10, "link-local")
+	validate(t, r2)
+
+	// Two trees back to back, ReadFrom reads only the first from a bytes.Buffer.
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Logf("Expected %d bytes, got %d (%v)\n", buf.Len(), n, err)
+		t.Fail()
+	}
+	n2, err := r2.WriteTo(&buf)
+	if err != nil || n+n2 != int64(buf.Len()) {
+		t.Logf("Expected %d bytes, got %d (%v)\n", int64(buf.Len())-n, n2, err)
+		t.Fail()
+	}
+	for _, x := range []struct {
+		r *Radix64Of[string]
+		n int64
+	}{{r, n}, {r2, n2}} {
+		r1 := New64Of[string]()
+		m, err := r1.ReadFrom(&buf)
+		if err != nil || m != x.n {
+			t.Logf("Expected %d bytes, got %d (%v)\n", x.n, m, err)
+			t.Fail()
+		}
+		validate(t, r1)
+		if !sameTree(x.r, r1) {
+			t.Logf("Expected the same tree after ReadFrom\n")
+			t.Fail()
+		}
+	}
+	if buf.Len() != 0 {
+		t.Logf("Expected all data to be read, %d bytes left\n", buf.Len())
+		t.Fail()
+	}
+	// Without io.ByteReader, data following the tree may be read too.
+	r.WriteTo(&buf)
+	r2.WriteTo(&buf)
+	r1 := New64Of[string]()
+	if m, err := r1.ReadFrom(struct{ io.Reader }{&buf}); err != nil || m < n || !sameTree(r, r1) {
+		t.Logf("Expected the same tree and at least %d bytes, got %d (%v)\n", n, m, err)
+		t.Fail()
+	}
+
+	// A 32 bit tree can not be read from a 64 bit encoding.
+	data, _ := r.MarshalBinary()
+	if err := New32Of[string]().UnmarshalBinary(data); !errors.Is(err, ErrFormat) {
+		t.Logf("Expected ErrFormat, got %v\n", err)
+		t.Fail()
+	}
+	// Truncated data is an error.
+	for i := 0; i < len(data); i++ {
+		if err := New64Of[string]().UnmarshalBinary(data[:i]); err == nil {
+			t.Logf("Expected an error for %d bytes\n", i)
+			t.Fail()
+		}
+	}
+}
+
+// intCodec stores values as a single byte.
+type intCodec struct{}
+
+func (intCodec) AppendValue(b []byte, v int) ([]byte, error) { return append(b, byte(v)), nil }
+
+func (intCodec) DecodeValue(b []byte) (int, error) {
+	if len(b) != 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(b[0]), nil
+}
+
+func TestCodec(t *testing.T) {
+	keys, bits := fullTable(1000)
+	r := New32Of[int]()
+	for i := range keys {
+		r.Insert(keys[i], bits[i], i%256)
+		validate(t, r)
+	}
+	var buf bytes.Buffer
+	if err := r.Encode(&buf, intCodec{}); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	size := buf.Len()
+	r1 := New32Of[int]()
+	if err := r1.Decode(&buf, intCodec{}); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	if !sameTree(r, r1) {
+		t.Logf("Expected the same tree after Decode\n")
+		t.Fail()
+	}
+	// The default codec stores an int as a varint, values from 64 take 2 bytes.
+	data, _ := r.MarshalBinary()
+	if len(data) <= size {
+		t.Logf("Expected more than %d bytes with the default codec, got %d\n", size, len(data))
+		t.Fail()
+	}
+	r2 := New32Of[int]()
+	if err := r2.UnmarshalBinary(data); err != nil || !sameTree(r, r2) {
+		t.Logf("Expected the same tree with the default codec, got %v\n", err)
+		t.Fail()
+	}
+	x := r.Find(keys[0], bits[0])
+	if err := x.Decode(bytes.NewReader(data), intCodec{}); err != ErrNotRoot {
+		t.Logf("Expected ErrNotRoot, got %v\n", err)
+		t.Fail()
+	}
+	buf.Reset()
+	if err := x.Encode(&buf, intCodec{}); err != ErrNotRoot || buf.Len() != 0 {
+		t.Logf("Expected ErrNotRoot and nothing written, got %v and %d bytes\n", err, buf.Len())
+		t.Fail()
+	}
+	if n, err := x.WriteTo(&buf); err != ErrNotRoot || n != 0 {
+		t.Logf("Expected ErrNotRoot and nothing written, got %v and %d bytes\n", err, n)
+		t.Fail()
+	}
+	if data, err := x.MarshalBinary(); err != ErrNotRoot || len(data) != 0 {
+		t.Logf("Expected ErrNotRoot and no data, got %v and %d bytes\n", err, len(data))
+		t.Fail()
+	}
+}
+
+// point is a value type that is not known to the interface{} codec, it is
+// registered with gob. unregistered is not.
+type (
+	point        struct{ X, Y int }
+	unregistered struct{ X, Y int }
+)
+
+func init() { gob.Register(point{}) }
+
+func TestAnyCodec(t *testing.T) {
+	values := []interface{}{nil, true, false, -1, int8(-8), int16(16), int32(-32), int64(1 << 40),
+		uint(1), uint8(8), uint16(16), uint32(32), uint64(1 << 63), uintptr(7),
+		float32(1.5), -2.25, "", "string", []byte{}, []byte("bytes")}
+	r := New32()
+	for i, v := range values {
+		r.Insert(0x0A000000+uint32(i)<<8, 24, v)
+		validate(t, r)
+	}
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	r1 := New32()
+	if err := r1.UnmarshalBinary(data); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	validate(t, r1)
+	for i, v := range values {
+		x := r1.Get(0x0A000000+uint32(i)<<8, 24)
+		if x == nil || fmt.Sprintf("%T %v", x.Value, x.Value) != fmt.Sprintf("%T %v", v, v) {
+			t.Logf("Expected %T %v, got %v\n", v, v, x)
+			t.Fail()
+		}
+	}
+
+	// Other types need to be registered with gob.
+	r.Insert(0x0B000000, 8, unregistered{1, 2})
+	validate(t, r)
+	if _, err := r.MarshalBinary(); err == nil {
+		t.Logf("Expected an error for an unregistered type\n")
+		t.Fail()
+	}
+	r.Insert(0x0B000000, 8, point{1, 2})
+	validate(t, r)
+	data, err = r.MarshalBinary()
+	r2 := New32()
+	if err == nil {
+		err = r2.UnmarshalBinary(data)
+	}
+	if x := r2.Get(0x0B000000, 8); err != nil || x == nil || x.Value != (point{1, 2}) {
+		t.Logf("Expected %v, got %v (%v)\n", point{1, 2}, x, err)
+		t.Fail()
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	header := []byte(encodingMagic + "\x01\x20")
+	tests := map[string][]byte{
+		// The root holding a key.
+		"root key": append(header, flagKey, 0, 1, 0),
+		// A value of 2 GiB, without the data.
+		"value size": append(header, flagBranch0, 0, flagKey, 8, 0x0A, 0x80, 0x80, 0x80, 0x80, 0x08, 1),
+	}
+	for name, data := range tests {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		before := m.TotalAlloc
+		err := New32().UnmarshalBinary(data)
+		runtime.ReadMemStats(&m)
+		t.Logf("%s: %v, %d bytes allocated\n", name, err, m.TotalAlloc-before)
+		if err == nil {
+			t.Logf("Expected an error for %s\n", name)
+			t.Fail()
+		}
+		if m.TotalAlloc-before > 1<<20 {
+			t.Logf("Expected less than 1 MiB allocated for %s, got %d\n", name, m.TotalAlloc-before)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package bitradix
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Frozen32 is a read-only radix tree with an uint32 as its key, stored in a
+// single flat byte slice that can be written to a file and memory mapped. Opening
+// it does not deserialize anything: children are node indices, not pointers, and
+// the values stay encoded. The layout, with all integers little endian, is
+//
+//	header: "BRDF", version (1 byte), 3 zero bytes, node count (uint32), values length (uint32)
+//	nodes:  per node key (uint32), skip (1 byte), bits (1 byte), 2 zero bytes,
+//	        zero branch (uint32) and one branch (uint32), 0 means no branch
+//	offsets: node count + 1 offsets (uint32), the value of node i is values[offsets[i]:offsets[i+1]]
+//	values: the encoded values
+//
+// The nodes are in pre-order, the root is node 0 and a branch always has a higher
+// index than its parent.
+type Frozen32 struct {
+	data   []byte
+	nodes  []byte
+	offs   []byte
+	values []byte
+}
+
+const (
+	frozenMagic   = "BRDF"
+	frozenVersion = 1
+	frozenHeader  = 16
+	frozenNode    = 16
+)
+
+// Freeze32 returns a Frozen32 holding the same prefixes as the tree r, values
+// are encoded with c. r must be the root of the tree.
+func Freeze32[V any](r *Radix32Of[V], c Codec[V]) (*Frozen32, error) {
+	if r.parent != nil {
+		return nil, ErrNotRoot
+	}
+	var (
+		nodes, offs, values []byte
+		err                 error
+		count               uint32
+	)
+	var freeze func(x *Radix32Of[V]) uint32
+	freeze = func(x *Radix32Of[V]) uint32 {
+		i := count
+		count++
+		nodes = binary.LittleEndian.AppendUint32(nodes, x.key)
+		nodes = append(nodes, byte(x.skip), byte(x.bits), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+		offs = binary.LittleEndian.AppendUint32(offs, uint32(len(values)))
+		if x.bits > 0 && err == nil {
+			values, err = c.AppendValue(values, x.Value)
+		}
+		for j, b := range x.branch {
+			if b != nil {
+				k := freeze(b) // grows nodes
+				binary.LittleEndian.PutUint32(nodes[i*frozenNode+8+4*uint32(j):], k)
+			}
+		}
+		return i
+	}
+	freeze(r)
+	if err != nil {
+		return nil, err
+	}
+	offs = binary.LittleEndian.AppendUint32(offs, uint32(len(values)))
+
+	data := make([]byte, 0, frozenHeader+len(nodes)+len(offs)+len(values))
+	data = append(data, frozenMagic...)
+	data = append(data, frozenVersion, 0, 0, 0)
+	data = binary.LittleEndian.AppendUint32(data, count)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(values)))
+	data = append(data, nodes...)
+	data = append(data, offs...)
+	data = append(data, values...)
+	return OpenFrozen32(data)
+}
+
+// OpenFrozen32 returns a Frozen32 using data, which must have been returned by
+// Frozen32.Bytes. The data is not copied and must not be modified. The branches
+// and the value offsets are checked, lookups in corrupted data can not panic,
+// but may return wrong results.
+func OpenFrozen32(data []byte) (*Frozen32, error) {
+	if len(data) < frozenHeader || string(data[:len(frozenMagic)]) != frozenMagic {
+		return nil, ErrFormat
+	}
+	if data[len(frozenMagic)] != frozenVersion {
+		return nil, fmt.Errorf("%w: unknown version %d", ErrFormat, data[len(frozenMagic)])
+	}
+	count := uint64(binary.LittleEndian.Uint32(data[8:]))
+	size := uint64(binary.LittleEndian.Uint32(data[12:]))
+	if count == 0 || uint64(len(data)) != frozenHeader+count*frozenNode+(count+1)*4+size {
+		return nil, ErrFormat
+	}
+	f := &Frozen32{data: data}
+	f.nodes = data[frozenHeader : frozenHeader+count*frozenNode]
+	f.offs = data[frozenHeader+count*frozenNode : frozenHeader+count*frozenNode+(count+1)*4]
+	f.values = data[len(data)-int(size):]
+	for i := uint64(0); i < count; i++ {
+		node := f.nodes[i*frozenNode:]
+		for j := 0; j < 2; j++ {
+			if c := uint64(binary.LittleEndian.Uint32(node[8+4*j:])); c != 0 && (c <= i || c >= count) {
+				return nil, fmt.Errorf("%w: node %d has branch %d", ErrFormat, i, c)
+			}
+		}
+		if binary.LittleEndian.Uint32(f.offs[4*i:]) > binary.LittleEndian.Uint32(f.offs[4*i+4:]) {
+			return nil, fmt.Errorf("%w: value offsets of node %d decrease", ErrFormat, i)
+		}
+	}
+	if uint64(binary.LittleEndian.Uint32(f.offs[4*count:])) > size {
+		return nil, fmt.Errorf("%w: value offsets beyond %d bytes", ErrFormat, size)
+	}
+	return f, nil
+}
+
+// Bytes returns the flat representation of f, to be used with OpenFrozen32.
+func (f *Frozen32) Bytes() []byte {
+	return f.data
+}
+
+// Find is like Radix.Find, it returns the prefix found and its encoded value,
+// or false when nothing can be found. The value is a slice of the data of f.
+// Find panics with ErrInvalidPrefixLen.
+func (f *Frozen32) Find(n uint32, bits int) (p Prefix[uint32], v []byte, ok bool) {
+	if err := checkPrefix(n, bits, false); err != nil {
+		panic(err)
+	}
+	return f.find(n, bits)
+}
+
+// Get returns the encoded value stored under exactly n/bits. Get panics like Find.
+func (f *Frozen32) Get(n uint32, bits int) ([]byte, bool) {
+	if p, v, ok := f.Find(n, bits); ok && p.Bits == bits {
+		return v, true
+	}
+	return nil, false
+}
+
+// LongestMatch returns the longest prefix that contains the host address n and its encoded value.
+func (f *Frozen32) LongestMatch(n uint32) (Prefix[uint32], []byte, bool) {
+	return f.find(n, 32)
+}
+
+// find is Radix.find on the flat nodes.
+func (f *Frozen32) find(n uint32, bits int) (p Prefix[uint32], v []byte, ok bool) {
+	last := -1
+	for x := 0; ; {
+		node := f.nodes[x*frozenNode : (x+1)*frozenNode]
+		key, skip, xbits := binary.LittleEndian.Uint32(node), int(node[4]), int(node[5])
+		if skip > bits {
+			break
+		}
+		if xbits > 0 {
+			if !match(key, n, xbits) {
+				break
+			}
+			last = x
+			if xbits == bits {
+				break
+			}
+		}
+		if skip == bits {
+			break
+		}
+		c := int(binary.LittleEndian.Uint32(node[8+4*int(bitK(n, 31-skip)):]))
+		if c <= x {
+			break
+		}
+		x = c
+	}
+	if last < 0 {
+		return p, nil, false
+	}
+	node := f.nodes[last*frozenNode:]
+	start, end := binary.LittleEndian.Uint32(f.offs[4*last:]), binary.LittleEndian.Uint32(f.offs[4*last+4:])
+	return Prefix[uint32]{binary.LittleEndian.Uint32(node), int(node[5])}, f.values[start:end:end], true
+}
@@ -0,0 +1,131 @@
+package bitradix
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+// valueCodec stores uint32 values, the values used by the route tests, as 4 bytes.
+type valueCodec struct{}
+
+func (valueCodec) AppendValue(b []byte, v interface{}) ([]byte, error) {
+	return fixedCodec[uint32]{}.AppendValue(b, v.(uint32))
+}
+
+func (valueCodec) DecodeValue(b []byte) (interface{}, error) {
+	return fixedCodec[uint32]{}.DecodeValue(b)
+}
+
+func TestFrozen32(t *testing.T) {
+	r := newIterTree(t)
+	f, err := Freeze32(r, valueCodec{})
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+		return
+	}
+	t.Logf("Frozen in %d bytes\n", len(f.Bytes()))
+	tests := map[string]uint32{
+		"10.21.1.1/32":   21,
+		"10.19.0.1/32":   10,
+		"10.20.0.0/14":   20,
+		"10.0.0.0/7":     0,
+		"192.168.2.0/23": 192,
+		"8.8.8.8/32":     15169,
+		"9.9.9.9/32":     0,
+	}
+	for ip, asn := range tests {
+		_, ipnet, _ := net.ParseCIDR(ip)
+		n, bits := ipToUint(t, ipnet)
+		var got uint32
+		if _, v, ok := f.Find(n, bits); ok {
+			x, _ := valueCodec{}.DecodeValue(v)
+			got = x.(uint32)
+		}
+		if got != asn {
+			t.Logf("Expected %d, got %d for %s\n", asn, got, ip)
+			t.Fail()
+		}
+	}
+	_, ipnet, _ := net.ParseCIDR("10.20.0.0/16")
+	if _, ok := f.Get(ipToUint(t, ipnet)); ok {
+		t.Logf("Expected no exact match for 10.20.0.0/16\n")
+		t.Fail()
+	}
+	if _, err := OpenFrozen32(f.Bytes()[:len(f.Bytes())-1]); err != ErrFormat {
+		t.Logf("Expected ErrFormat, got %v\n", err)
+		t.Fail()
+	}
+
+	// Corrupted branches and value offsets.
+	count := len(f.nodes) / frozenNode
+	offs := frozenHeader + len(f.nodes)
+	corrupt := map[string]func(data []byte){
+		"branch to itself": func(data []byte) {
+			binary.LittleEndian.PutUint32(data[frozenHeader+frozenNode+8:], 1)
+			binary.LittleEndian.PutUint32(data[frozenHeader+frozenNode+12:], 1)
+		},
+		"branch upwards": func(data []byte) {
+			binary.LittleEndian.PutUint32(data[frozenHeader+2*frozenNode+8:], 1)
+			binary.LittleEndian.PutUint32(data[frozenHeader+2*frozenNode+12:], 1)
+		},
+		"branch beyond": func(data []byte) { binary.LittleEndian.PutUint32(data[frozenHeader+12:], uint32(count)) },
+		"offsets decrease": func(data []byte) {
+			binary.LittleEndian.PutUint32(data[offs+4:], binary.LittleEndian.Uint32(data[offs+8:])+1)
+		},
+		"offsets beyond": func(data []byte) {
+			binary.LittleEndian.PutUint32(data[offs+4*count:], uint32(len(f.values)+1))
+		},
+	}
+	for name, c := range corrupt {
+		data := append([]byte(nil), f.Bytes()...)
+		c(data)
+		if _, err := OpenFrozen32(data); !errors.Is(err, ErrFormat) {
+			t.Logf("Expected ErrFormat for %s, got %v\n", name, err)
+			t.Fail()
+		}
+	}
+}
+
+// Compare a frozen full table with the tree it was built from.
+func TestFrozen32FullTable(t *testing.T) {
+	keys, bits := fullTable(20000)
+	r := New32Of[uint32]()
+	for i := range keys {
+		r.Insert(keys[i], bits[i], uint32(i))
+	}
+	f, err := Freeze32(r, DefaultCodec[uint32]())
+	if err == nil {
+		f, err = OpenFrozen32(append([]byte(nil), f.Bytes()...))
+	}
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+		return
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		n, l := rnd.Uint32(), 1+rnd.Intn(32)
+		if i%2 == 0 {
+			j := rnd.Intn(len(keys))
+			n = randomHost(rnd, keys[j], bits[j])
+		}
+		x := r.Find(n, l)
+		p, v, ok := f.Find(n, l)
+		if (x != nil) != ok {
+			t.Logf("Expected %v, got %v for %032b/%d\n", x != nil, ok, n, l)
+			t.Fail()
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if y, _ := DefaultCodec[uint32]().DecodeValue(v); p.Key != x.key || p.Bits != x.bits || y != x.Value {
+			t.Logf("Expected %032b/%d -> %d, got %032b/%d -> %d\n", x.key, x.bits, x.Value, p.Key, p.Bits, y)
+			t.Fail()
+		}
+	}
+}
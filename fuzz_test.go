@@ -0,0 +1,168 @@
+package bitradix
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+// The fuzz targets apply a sequence of operations to a tree and to a slice of
+// prefixes and compare the results. An operation is an opcode byte (modulo 4:
+// insert, remove, find and an update that removes the prefix when it exists and
+// inserts it otherwise), a prefix length byte (modulo the key size, plus 1) and
+// the key in big endian order.
+
+// maxFuzzOps is the maximum number of operations in a fuzz input.
+const maxFuzzOps = 512
+
+// refPrefix is a prefix in the reference implementation.
+type refPrefix[K Key] struct {
+	key   K
+	bits  int
+	value int
+}
+
+// refFind returns the index of the longest prefix in ref that contains n/bits, or -1.
+func refFind[K Key](ref []refPrefix[K], n K, bits int) int {
+	j := -1
+	for i, p := range ref {
+		if p.bits <= bits && match(p.key, n, p.bits) && (j < 0 || p.bits > ref[j].bits) {
+			j = i
+		}
+	}
+	return j
+}
+
+func fuzzRadix[K Key](t *testing.T, data []byte) {
+	size := bitSize[K]() / 8
+	// Each operation checks the whole tree, limit their number to keep the fuzzer fast.
+	if len(data) > maxFuzzOps*(2+size) {
+		data = data[:maxFuzzOps*(2+size)]
+	}
+	r := New[K, int]()
+	var ref []refPrefix[K]
+	for i := 0; len(data) >= 2+size; i++ {
+		op, bits, n := data[0]%4, 1+int(data[1])%bitSize[K](), keyFromBytes[K](data[2:2+size])
+		data = data[2+size:]
+		switch op {
+		case 0:
+			n = maskKey(n, bits)
+			r.Insert(n, bits, i)
+			if j := refFind(ref, n, bits); j >= 0 && ref[j].bits == bits {
+				ref[j].value = i
+			} else {
+				ref = append(ref, refPrefix[K]{n, bits, i})
+			}
+		case 1:
+			n = maskKey(n, bits)
+			x := r.Remove(n, bits)
+			j := refFind(ref, n, bits)
+			if j >= 0 && ref[j].bits != bits {
+				j = -1
+			}
+			if (x != nil) != (j >= 0) || (x != nil && x.Value != ref[j].value) {
+				t.Fatalf("Removal of %s: expected %v, got %v\n", formatPrefix(n, bits), j >= 0, x)
+			}
+			if j >= 0 {
+				ref = slices.Delete(ref, j, j+1)
+			}
+		case 2:
+			x := r.Find(n, bits)
+			j := refFind(ref, n, bits)
+			if (x != nil) != (j >= 0) || (x != nil && (x.key != ref[j].key || x.bits != ref[j].bits || x.Value != ref[j].value)) {
+				t.Fatalf("Find of %s: expected %v, got %v\n", formatPrefix(n, bits), j >= 0, x)
+			}
+			y, _ := r.LongestMatch(n)
+			if j := refFind(ref, n, bitSize[K]()); (y != nil) != (j >= 0) || (y != nil && y.Value != ref[j].value) {
+				t.Fatalf("LongestMatch of %s: expected %v, got %v\n", formatPrefix(n, bitSize[K]()), j >= 0, y)
+			}
+		case 3:
+			n = maskKey(n, bits)
+			existed := false
+			x := r.Update(n, bits, func(old int, exists bool) (int, bool) {
+				existed = exists
+				return i, !exists
+			})
+			j := refFind(ref, n, bits)
+			if j >= 0 && ref[j].bits != bits {
+				j = -1
+			}
+			if existed != (j >= 0) || (x == nil) != existed {
+				t.Fatalf("Update of %s: expected %v, got %v\n", formatPrefix(n, bits), j >= 0, existed)
+			}
+			if j >= 0 {
+				ref = slices.Delete(ref, j, j+1)
+			} else {
+				ref = append(ref, refPrefix[K]{n, bits, i})
+			}
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("%s\n%s", err, r)
+		}
+	}
+	count := 0
+	for range r.All() {
+		count++
+	}
+	if count != len(ref) {
+		t.Fatalf("Expected %d prefixes, got %d\n%s", len(ref), count, r)
+	}
+}
+
+// seedOps returns the operations to insert the prefixes in order, and find them and
+// the addresses in them.
+func seedOps[K Key](prefixes []string, parse func(string) (K, int)) []byte {
+	var data []byte
+	for op := byte(0); op < 3; op += 2 {
+		for _, s := range prefixes {
+			n, bits := parse(s)
+			data = append(data, op, byte(bits-1))
+			data = appendKey(data, n, bitSize[K]())
+		}
+	}
+	for _, s := range prefixes {
+		n, _ := parse(s)
+		data = append(data, 2, byte(bitSize[K]()-1))
+		data = appendKey(data, n, bitSize[K]())
+	}
+	return data
+}
+
+// seedRoutes adds seeds for routes inserted in sorted order, and inserted in
+// reverse sorted order followed by the removal of half of them.
+func seedRoutes[K Key](f *testing.F, routes map[string]uint32, parse func(string) (K, int)) {
+	var prefixes []string
+	for s := range routes {
+		prefixes = append(prefixes, s)
+	}
+	slices.Sort(prefixes)
+	f.Add(seedOps(prefixes, parse))
+	slices.Reverse(prefixes)
+	data := seedOps(prefixes, parse)
+	for _, s := range prefixes[:len(prefixes)/2] {
+		n, bits := parse(s)
+		data = append(data, 1, byte(bits-1))
+		data = appendKey(data, n, bitSize[K]())
+	}
+	f.Add(data)
+}
+
+func FuzzRadix32(f *testing.F) {
+	parse := func(s string) (uint32, int) {
+		_, ipnet, _ := net.ParseCIDR(s)
+		return ipToUint(nil, ipnet)
+	}
+	seedRoutes(f, findMySelfRoutes, parse)
+	seedRoutes(f, findOverwriteRoutes, parse)
+	f.Fuzz(fuzzRadix[uint32])
+}
+
+func FuzzRadix64(f *testing.F) {
+	parse := func(s string) (uint64, int) {
+		_, ipnet, _ := net.ParseCIDR(s)
+		return ipToUint64(nil, ipnet)
+	}
+	seedRoutes(f, findMySelfRoutes64, parse)
+	seedRoutes(f, findOverwriteRoutes64, parse)
+	f.Fuzz(fuzzRadix[uint64])
+}
@@ -0,0 +1,3 @@
+module github.com/miekg/bitradix
+
+go 1.24
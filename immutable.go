@@ -0,0 +1,218 @@
+package bitradix
+
+import "iter"
+
+// Immutable implements a persistent radix tree: a tree is never changed once
+// it is built. Insert and Remove return a new root, they copy the nodes on the
+// path from the root to the changed node and share all other nodes with the
+// previous version, which stays valid. The node layout is that of Radix,
+// without the parent pointer.
+type Immutable[K Key, V any] struct {
+	branch [2]*Immutable[K, V]
+	key    K
+	skip   int
+	bits   int
+	Value  V // The value stored.
+}
+
+// Immutable32 implements a persistent radix tree with an uint32 as its key.
+type Immutable32 = Immutable[uint32, interface{}]
+
+// Immutable32Of implements a persistent radix tree with an uint32 as its key
+// and values of type V.
+type Immutable32Of[V any] = Immutable[uint32, V]
+
+// NewImmutable returns an empty Immutable tree.
+func NewImmutable[K Key, V any]() *Immutable[K, V] {
+	return &Immutable[K, V]{}
+}
+
+// Key returns the key under which this node is stored.
+func (r *Immutable[K, V]) Key() K {
+	return r.key
+}
+
+// Bits returns the number of significant bits for the key.
+// A value of zero indicates a key that has not been set.
+func (r *Immutable[K, V]) Bits() int {
+	return r.bits
+}
+
+// Leaf returns true is r is an leaf node.
+func (r *Immutable[K, V]) Leaf() bool {
+	return r.branch[0] == nil && r.branch[1] == nil
+}
+
+// Insert returns a new tree with the value v inserted under n/bits (possibly
+// silently overwriting an existing value), r must be the root of the tree.
+// Insert panics when TryInsert would return an error.
+func (r *Immutable[K, V]) Insert(n K, bits int, v V) *Immutable[K, V] {
+	x, err := r.TryInsert(n, bits, v)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// TryInsert is like Insert, but returns the same errors as Radix.TryInsert
+// instead of panicking.
+func (r *Immutable[K, V]) TryInsert(n K, bits int, v V) (*Immutable[K, V], error) {
+	if err := r.check(n, bits, true); err != nil {
+		return nil, err
+	}
+	return r.insert(n, bits, v), nil
+}
+
+// Remove returns a new tree without n/bits and the node that was removed. When
+// n/bits is not found r and nil are returned. r must be the root of the tree.
+// Remove panics like Radix.Remove.
+func (r *Immutable[K, V]) Remove(n K, bits int) (*Immutable[K, V], *Immutable[K, V]) {
+	if err := r.check(n, bits, true); err != nil {
+		panic(err)
+	}
+	return r.remove(n, bits, true)
+}
+
+// Find is like Radix.Find.
+func (r *Immutable[K, V]) Find(n K, bits int) *Immutable[K, V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	return r.find(n, bits)
+}
+
+// Get is like Radix.Get.
+func (r *Immutable[K, V]) Get(n K, bits int) *Immutable[K, V] {
+	if x := r.Find(n, bits); x != nil && x.bits == bits {
+		return x
+	}
+	return nil
+}
+
+// LongestMatch is like Radix.LongestMatch.
+func (r *Immutable[K, V]) LongestMatch(n K) (*Immutable[K, V], int) {
+	if r.skip != 0 {
+		panic(ErrNotRoot)
+	}
+	x := r.find(n, bitSize[K]())
+	if x == nil {
+		return nil, 0
+	}
+	return x, x.bits
+}
+
+// All is like Radix.All.
+func (r *Immutable[K, V]) All() iter.Seq2[Prefix[K], V] {
+	return func(yield func(Prefix[K], V) bool) {
+		r.walk(yield)
+	}
+}
+
+// check is like Radix.check, the root is the only node that covers no bits.
+func (r *Immutable[K, V]) check(n K, bits int, host bool) error {
+	if r.skip != 0 {
+		return ErrNotRoot
+	}
+	return checkPrefix(n, bits, host)
+}
+
+// insert returns a copy of r with v inserted under n/bits, the prefix of r contains n/bits.
+func (r *Immutable[K, V]) insert(n K, bits int, v V) *Immutable[K, V] {
+	c := *r
+	if c.skip == bits {
+		c.key, c.bits, c.Value = n, bits, v
+		return &c
+	}
+	k := bitK(n, bitSize[K]()-1-c.skip)
+	b := c.branch[k]
+	if b == nil {
+		c.branch[k] = &Immutable[K, V]{key: n, skip: bits, bits: bits, Value: v}
+		return &c
+	}
+	l := common(b.key, n, min(b.skip, bits))
+	if l == b.skip {
+		c.branch[k] = b.insert(n, bits, v)
+		return &c
+	}
+	// n/bits is shorter than b, or diverges from it, put a node in between
+	g := &Immutable[K, V]{key: maskKey(n, l), skip: l}
+	g.branch[bitK(b.key, bitSize[K]()-1-l)] = b
+	if l == bits {
+		g.key, g.bits, g.Value = n, bits, v
+	} else {
+		g.branch[bitK(n, bitSize[K]()-1-l)] = &Immutable[K, V]{key: n, skip: bits, bits: bits, Value: v}
+	}
+	c.branch[k] = g
+	return &c
+}
+
+// remove returns the node that replaces r after removing n/bits, and the
+// removed node. When n/bits is not found r and nil are returned.
+func (r *Immutable[K, V]) remove(n K, bits int, root bool) (*Immutable[K, V], *Immutable[K, V]) {
+	if r.skip > bits || !match(r.key, n, r.skip) {
+		return r, nil
+	}
+	c := *r
+	if c.skip == bits {
+		if c.bits != bits {
+			return r, nil
+		}
+		var v V
+		c.bits, c.Value = 0, v
+		return c.compact(root), r
+	}
+	k := bitK(n, bitSize[K]()-1-c.skip)
+	if c.branch[k] == nil {
+		return r, nil
+	}
+	b, x := c.branch[k].remove(n, bits, false)
+	if x == nil {
+		return r, nil
+	}
+	c.branch[k] = b
+	return c.compact(root), x
+}
+
+// compact returns the node that should take the place of r: nil or its only
+// branch when r does not hold a key. The root is always kept.
+func (r *Immutable[K, V]) compact(root bool) *Immutable[K, V] {
+	if root || r.bits != 0 || (r.branch[0] != nil && r.branch[1] != nil) {
+		return r
+	}
+	if r.branch[0] != nil {
+		return r.branch[0]
+	}
+	return r.branch[1]
+}
+
+// find is like Radix.find.
+func (r *Immutable[K, V]) find(n K, bits int) *Immutable[K, V] {
+	var last *Immutable[K, V]
+	for x := r; x != nil && x.skip <= bits; {
+		if x.bits > 0 {
+			if !match(x.key, n, x.bits) {
+				break
+			}
+			if x.bits == bits {
+				return x
+			}
+			last = x
+		}
+		if x.skip == bits {
+			break
+		}
+		x = x.branch[bitK(n, bitSize[K]()-1-x.skip)]
+	}
+	return last
+}
+
+// walk is like Radix.walk.
+func (r *Immutable[K, V]) walk(yield func(Prefix[K], V) bool) bool {
+	if r == nil {
+		return true
+	}
+	if r.bits > 0 && !yield(Prefix[K]{r.key, r.bits}, r.Value) {
+		return false
+	}
+	return r.branch[0].walk(yield) && r.branch[1].walk(yield)
+}
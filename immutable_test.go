@@ -0,0 +1,88 @@
+package bitradix
+
+import (
+	"slices"
+	"testing"
+)
+
+func immutableValues(r *Immutable32) (v []interface{}) {
+	for _, x := range r.All() {
+		v = append(v, x)
+	}
+	return v
+}
+
+func TestImmutable(t *testing.T) {
+	v0 := NewImmutable[uint32, interface{}]()
+	v1 := v0.Insert(0x0A000000, 8, 10).Insert(0xC0A80000, 16, 192)
+	v2 := v1.Insert(0x0A140000, 14, 20)
+	v3, x := v2.Remove(0x0A000000, 8)
+	if x == nil || x.Value != 10 {
+		t.Logf("Expected %d, got %v\n", 10, x)
+		t.Fail()
+	}
+
+	tests := []struct {
+		r        *Immutable32
+		expected []interface{}
+	}{
+		{v0, nil},
+		{v1, []interface{}{10, 192}},
+		{v2, []interface{}{10, 20, 192}},
+		{v3, []interface{}{20, 192}},
+	}
+	for i, test := range tests {
+		if got := immutableValues(test.r); !slices.Equal(got, test.expected) {
+			t.Logf("Expected %v, got %v for version %d\n", test.expected, got, i)
+			t.Fail()
+		}
+		if err := test.r.Validate(); err != nil {
+			t.Logf("Invalid tree for version %d: %s\n", i, err)
+			t.Fail()
+		}
+	}
+
+	// The 192.168.0.0/16 branch was not touched and is shared.
+	if v1.branch[1] != v2.branch[1] || v2.branch[1] != v3.branch[1] {
+		t.Logf("Expected the 1 branch to be shared between versions\n")
+		t.Fail()
+	}
+	if x, bits := v2.LongestMatch(0x0A150101); x == nil || x.Value != 20 || bits != 14 {
+		t.Logf("Expected %d/%d, got %v/%d\n", 20, 14, x, bits)
+		t.Fail()
+	}
+	if x, bits := v3.LongestMatch(0x0A010101); x != nil {
+		t.Logf("Expected nil, got %v/%d\n", x, bits)
+		t.Fail()
+	}
+	if r, x := v3.Remove(0x0A000000, 8); r != v3 || x != nil {
+		t.Logf("Expected removal of a missing prefix to return the same tree\n")
+		t.Fail()
+	}
+	if _, err := v2.Get(0x0A140000, 14).TryInsert(0x0A140000, 14, 1); err != ErrNotRoot {
+		t.Logf("Expected %v, got %v\n", ErrNotRoot, err)
+		t.Fail()
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	c := NewConcurrent[uint32, interface{}]()
+	c.Insert(0x0A000000, 8, 10)
+	s := c.Snapshot()
+	c.Insert(0x0A140000, 14, 20)
+	c.Remove(0x0A000000, 8)
+	if got, expected := immutableValues(s), []interface{}{10}; !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+	if got, expected := immutableValues(c.Snapshot()), []interface{}{20}; !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+	for _, r := range []*Immutable32{s, c.Snapshot()} {
+		if err := r.Validate(); err != nil {
+			t.Logf("Invalid tree: %s\n", err)
+			t.Fail()
+		}
+	}
+}
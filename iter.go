@@ -0,0 +1,143 @@
+package bitradix
+
+import (
+	"container/heap"
+	"iter"
+)
+
+// Prefix is a key together with its number of significant bits.
+type Prefix[K Key] struct {
+	Key  K
+	Bits int
+}
+
+// All returns an iterator over the keys and values stored in the tree r in
+// lexicographic order: a prefix comes before the longer prefixes it contains,
+// and the zero branch before the one branch. Empty nodes are skipped.
+func (r *Radix[K, V]) All() iter.Seq2[Prefix[K], V] {
+	return func(yield func(Prefix[K], V) bool) {
+		r.walk(func(x *Radix[K, V]) bool { return yield(Prefix[K]{x.key, x.bits}, x.Value) }, false)
+	}
+}
+
+// Backward returns an iterator over the keys and values stored in the tree r
+// in the reverse order of All.
+func (r *Radix[K, V]) Backward() iter.Seq2[Prefix[K], V] {
+	return func(yield func(Prefix[K], V) bool) {
+		r.walk(func(x *Radix[K, V]) bool { return yield(Prefix[K]{x.key, x.bits}, x.Value) }, true)
+	}
+}
+
+// Subtree returns an iterator, in the order of All, over the prefix n/bits and
+// all the longer prefixes it contains. r must be the root of the tree.
+func (r *Radix[K, V]) Subtree(n K, bits int) iter.Seq2[Prefix[K], V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	return func(yield func(Prefix[K], V) bool) {
+		r.descend(n, bits).walk(func(x *Radix[K, V]) bool { return yield(Prefix[K]{x.key, x.bits}, x.Value) }, false)
+	}
+}
+
+// descend returns the first node on the path of n that is contained in n/bits,
+// or nil if there is no such node.
+func (r *Radix[K, V]) descend(n K, bits int) *Radix[K, V] {
+	x := r
+	for x != nil && x.skip < bits {
+		if !match(x.key, n, x.skip) {
+			return nil
+		}
+		x = x.branch[bitK(n, bitSize[K]()-1-x.skip)]
+	}
+	if x != nil && !match(x.key, n, bits) {
+		return nil
+	}
+	return x
+}
+
+// walk calls f for each keyed node under r, it returns false as soon as f does.
+func (r *Radix[K, V]) walk(f func(*Radix[K, V]) bool, backward bool) bool {
+	if r == nil {
+		return true
+	}
+	if !backward && r.bits > 0 && !f(r) {
+		return false
+	}
+	b0, b1 := r.branch[0], r.branch[1]
+	if backward {
+		b0, b1 = b1, b0
+	}
+	if !b0.walk(f, backward) || !b1.walk(f, backward) {
+		return false
+	}
+	if backward && r.bits > 0 && !f(r) {
+		return false
+	}
+	return true
+}
+
+// Supernets returns an iterator over the prefixes in the tree that contain
+// n/bits, including n/bits itself, ordered from shortest to longest. r must be
+// the root of the tree.
+func (r *Radix[K, V]) Supernets(n K, bits int) iter.Seq2[Prefix[K], V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	return func(yield func(Prefix[K], V) bool) {
+		for x := r; x != nil && x.skip <= bits && match(x.key, n, x.skip); {
+			if x.bits > 0 && !yield(Prefix[K]{x.key, x.bits}, x.Value) {
+				return
+			}
+			if x.skip == bits {
+				return
+			}
+			x = x.branch[bitK(n, bitSize[K]()-1-x.skip)]
+		}
+	}
+}
+
+// Subnets returns an iterator over the prefixes in the tree that are contained
+// in n/bits, including n/bits itself, ordered from shortest to longest. Prefixes
+// of equal length are returned in lexicographic order. The iterator only keeps
+// the branches not yet visited, in a heap, so stopping early does not cost a walk
+// of the whole subtree. r must be the root of the tree.
+func (r *Radix[K, V]) Subnets(n K, bits int) iter.Seq2[Prefix[K], V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	return func(yield func(Prefix[K], V) bool) {
+		var h byLength[K, V]
+		if x := r.descend(n, bits); x != nil {
+			h = append(h, x)
+		}
+		for len(h) > 0 {
+			x := heap.Pop(&h).(*Radix[K, V])
+			if x.bits > 0 && !yield(Prefix[K]{x.key, x.bits}, x.Value) {
+				return
+			}
+			for _, b := range x.branch {
+				if b != nil {
+					heap.Push(&h, b)
+				}
+			}
+		}
+	}
+}
+
+// byLength is a heap of nodes ordered on prefix length and then on key.
+type byLength[K Key, V any] []*Radix[K, V]
+
+func (h byLength[K, V]) Len() int { return len(h) }
+func (h byLength[K, V]) Less(i, j int) bool {
+	if h[i].skip != h[j].skip {
+		return h[i].skip < h[j].skip
+	}
+	return compareKey(h[i].key, h[j].key) < 0
+}
+func (h byLength[K, V]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *byLength[K, V]) Push(x any)   { *h = append(*h, x.(*Radix[K, V])) }
+func (h *byLength[K, V]) Pop() any {
+	x := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return x
+}
@@ -0,0 +1,182 @@
+package bitradix
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func newIterTree(t *testing.T) *Radix32 {
+	r := New32()
+	addRoute(t, r, "192.168.0.0/16", 192)
+	addRoute(t, r, "10.20.0.0/14", 20)
+	addRoute(t, r, "10.0.0.0/8", 10)
+	addRoute(t, r, "192.168.2.0/24", 1922)
+	addRoute(t, r, "10.21.0.0/16", 21)
+	addRoute(t, r, "8.8.8.0/24", 15169)
+	return r
+}
+
+func TestAll(t *testing.T) {
+	r := newIterTree(t)
+	var got []interface{}
+	for p, v := range r.All() {
+		t.Logf("%s/%d -> %d\n", uintToIP(p.Key), p.Bits, v)
+		got = append(got, v)
+	}
+	expected := []interface{}{uint32(15169), uint32(10), uint32(20), uint32(21), uint32(192), uint32(1922)}
+	if !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+
+	got = got[:0]
+	for _, v := range r.Backward() {
+		got = append(got, v)
+	}
+	slices.Reverse(expected)
+	if !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+}
+
+func TestAllBreak(t *testing.T) {
+	r := newIterTree(t)
+	i := 0
+	for range r.All() {
+		i++
+		if i == 2 {
+			break
+		}
+	}
+	if i != 2 {
+		t.Logf("Expected %d iterations, got %d\n", 2, i)
+		t.Fail()
+	}
+	for p := range r.Backward() {
+		if p.Bits != 24 {
+			t.Logf("Expected /24 first, got /%d\n", p.Bits)
+			t.Fail()
+		}
+		break
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	r := newIterTree(t)
+	tests := map[Prefix[uint32]][]interface{}{
+		Prefix[uint32]{0x0A000000, 8}:  {uint32(10), uint32(20), uint32(21)},
+		Prefix[uint32]{0x0A000000, 7}:  {uint32(10), uint32(20), uint32(21)},
+		Prefix[uint32]{0x0A140000, 14}: {uint32(20), uint32(21)},
+		Prefix[uint32]{0x0A150000, 16}: {uint32(21)},
+		Prefix[uint32]{0x0B000000, 8}:  nil,
+	}
+	for p, expected := range tests {
+		var got []interface{}
+		for _, v := range r.Subtree(p.Key, p.Bits) {
+			got = append(got, v)
+		}
+		if !slices.Equal(got, expected) {
+			t.Logf("Expected %v, got %v for %s/%d\n", expected, got, uintToIP(p.Key), p.Bits)
+			t.Fail()
+		}
+	}
+}
+
+func TestSupernets(t *testing.T) {
+	r := newIterTree(t)
+	tests := map[Prefix[uint32]][]interface{}{
+		Prefix[uint32]{0x0A150100, 24}: {uint32(10), uint32(20), uint32(21)},
+		Prefix[uint32]{0x0A150000, 16}: {uint32(10), uint32(20), uint32(21)},
+		Prefix[uint32]{0x0A140000, 14}: {uint32(10), uint32(20)},
+		Prefix[uint32]{0x0A000000, 7}:  nil,
+		Prefix[uint32]{0xC0A80203, 32}: {uint32(192), uint32(1922)},
+	}
+	for p, expected := range tests {
+		var got []interface{}
+		for _, v := range r.Supernets(p.Key, p.Bits) {
+			got = append(got, v)
+		}
+		if !slices.Equal(got, expected) {
+			t.Logf("Expected %v, got %v for %s/%d\n", expected, got, uintToIP(p.Key), p.Bits)
+			t.Fail()
+		}
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	r := newIterTree(t)
+	addRoute(t, r, "10.128.0.0/9", 128)
+	addRoute(t, r, "10.0.0.0/24", 100)
+	tests := map[Prefix[uint32]][]interface{}{
+		Prefix[uint32]{0x0A000000, 8}:  {uint32(10), uint32(128), uint32(20), uint32(21), uint32(100)},
+		Prefix[uint32]{0x0A000000, 7}:  {uint32(10), uint32(128), uint32(20), uint32(21), uint32(100)},
+		Prefix[uint32]{0x0A140000, 14}: {uint32(20), uint32(21)},
+		Prefix[uint32]{0x0B000000, 8}:  nil,
+	}
+	for p, expected := range tests {
+		var got []interface{}
+		prev := 0
+		for q, v := range r.Subnets(p.Key, p.Bits) {
+			if q.Bits < prev {
+				t.Logf("Expected increasing prefix lengths, got /%d after /%d\n", q.Bits, prev)
+				t.Fail()
+			}
+			prev = q.Bits
+			got = append(got, v)
+		}
+		if !slices.Equal(got, expected) {
+			t.Logf("Expected %v, got %v for %s/%d\n", expected, got, uintToIP(p.Key), p.Bits)
+			t.Fail()
+		}
+	}
+}
+
+// TestSubnetsOrder compares Subnets with All sorted on length.
+func TestSubnetsOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	r := New32Of[int]()
+	for i := 0; i < 500; i++ {
+		bits := 8 + rnd.Intn(20)
+		r.Insert(maskKey(0x0A000000|rnd.Uint32()>>8, bits), bits, i)
+		validate(t, r)
+	}
+	var expected []Prefix[uint32]
+	for p := range r.All() {
+		expected = append(expected, p)
+	}
+	slices.SortStableFunc(expected, func(a, b Prefix[uint32]) int { return a.Bits - b.Bits })
+	var got []Prefix[uint32]
+	for p := range r.Subnets(0x0A000000, 8) {
+		got = append(got, p)
+	}
+	if !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+}
+
+func TestSupernets64(t *testing.T) {
+	r := New64()
+	addRoute64(t, r, "2001:db8::/32", 32)
+	addRoute64(t, r, "2001:db8:1::/48", 48)
+	addRoute64(t, r, "2001:db8:1:1::/64", 64)
+	var got []interface{}
+	for _, v := range r.Supernets(0x20010db800010001, 64) {
+		got = append(got, v)
+	}
+	if expected := []interface{}{uint32(32), uint32(48), uint32(64)}; !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+	got = got[:0]
+	for _, v := range r.Subnets(0x20010db800000000, 32) {
+		got = append(got, v)
+		break
+	}
+	if expected := []interface{}{uint32(32)}; !slices.Equal(got, expected) {
+		t.Logf("Expected %v, got %v\n", expected, got)
+		t.Fail()
+	}
+}
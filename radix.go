@@ -0,0 +1,462 @@
+// Package bitradix implements a radix tree that branches on the bits of an
+// unsigned integer key. The tree is generic over the width of the key and the
+// type of the values stored. Radix32, Radix64 and Radix128 are provided for the
+// common 32, 64 and 128 bits keys with interface{} values, Radix32Of, Radix64Of
+// and Radix128Of for typed values.
+//
+// A radix tree is defined in:
+//
+//	Donald R. Morrison. "PATRICIA -- practical algorithm to retrieve
+//	information coded in alphanumeric". Journal of the ACM, 15(4):514-534,
+//	October 1968
+//
+// This website provides some background information on Radix trees.
+// http://faculty.simpson.edu/lydia.sinapova/www/cmsc250/LN250_Weiss/L08-Radix.htm
+package bitradix
+
+import (
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+// Errors returned by the TryInsert and TryRemove methods, the other methods panic with them.
+var (
+	ErrNotRoot          = errors.New("bitradix: not the root node")
+	ErrInvalidPrefixLen = errors.New("bitradix: invalid prefix length")
+	ErrHostBitsSet      = errors.New("bitradix: host bits set")
+)
+
+// Key is the set of key types a Radix tree can be indexed with.
+type Key interface {
+	uint8 | uint16 | uint32 | uint64 | Uint128
+}
+
+// Radix implements a radix tree with a K as its key and values of type V.
+//
+// The tree is path compressed: a node only exists where a key is stored or
+// where two branches split, chains of single-child nodes are collapsed. Each
+// node records in skip the length of the prefix it covers, the children of a
+// node branch on the bit directly following that prefix. A node that does not
+// hold a key (bits is 0) always has two branches, the root excepted.
+type Radix[K Key, V any] struct {
+	branch [2]*Radix[K, V] // branch[0] is left branch for 0, and branch[1] the right for 1
+	parent *Radix[K, V]
+	key    K   // the key under which this value is stored, the common prefix when the key has not been set
+	skip   int // the number of leading bits of key this node covers
+	bits   int // the number of significant bits, if 0 the key has not been set.
+	Value  V   // The value stored.
+}
+
+// New returns an empty, initialized Radix tree.
+func New[K Key, V any]() *Radix[K, V] {
+	return &Radix[K, V]{}
+}
+
+// Key returns the key under which this node is stored.
+func (r *Radix[K, V]) Key() K {
+	return r.key
+}
+
+// Bits returns the number of significant bits for the key.
+// A value of zero indicates a key that has not been set.
+func (r *Radix[K, V]) Bits() int {
+	return r.bits
+}
+
+// Leaf returns true is r is an leaf node, when false is returned
+// the node is a non-leaf node.
+func (r *Radix[K, V]) Leaf() bool {
+	return r.branch[0] == nil && r.branch[1] == nil
+}
+
+// Insert inserts a new value n in the tree r (possibly silently overwriting an existing value).
+// It returns the inserted node, r must be the root of the tree. Insert panics
+// when TryInsert would return an error.
+func (r *Radix[K, V]) Insert(n K, bits int, v V) *Radix[K, V] {
+	x, err := r.TryInsert(n, bits, v)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// TryInsert is like Insert, but returns an error instead of panicking. It returns
+// ErrNotRoot when r is not the root of the tree, ErrInvalidPrefixLen when bits is
+// not between 1 and the number of bits in K and ErrHostBitsSet when n has bits set
+// beyond the first bits bits.
+func (r *Radix[K, V]) TryInsert(n K, bits int, v V) (*Radix[K, V], error) {
+	if err := r.check(n, bits, true); err != nil {
+		return nil, err
+	}
+	return r.insert(n, bits, v), nil
+}
+
+// Remove removes a value from the tree r. It returns the node removed, or nil
+// when nothing is found, r must be the root of the tree. Remove panics when
+// TryRemove would return an error.
+func (r *Radix[K, V]) Remove(n K, bits int) *Radix[K, V] {
+	x, err := r.TryRemove(n, bits)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// TryRemove is like Remove, but returns an error instead of panicking. The
+// errors are the same as for TryInsert. Not finding n is not an error, in that
+// case nil is returned for the node.
+func (r *Radix[K, V]) TryRemove(n K, bits int) (*Radix[K, V], error) {
+	if err := r.check(n, bits, true); err != nil {
+		return nil, err
+	}
+	return r.remove(n, bits), nil
+}
+
+// Update changes the value stored under n/bits with a single descent of the tree.
+// It calls f with the value stored and true, or with the zero value and false
+// when n/bits is not in the tree. When f returns true, the value it returns is
+// stored under n/bits, when it returns false n/bits is removed like Remove does.
+// Update returns the node holding n/bits, or nil when it is not in the tree after
+// the update, r must be the root of the tree. Update panics when TryUpdate would
+// return an error.
+func (r *Radix[K, V]) Update(n K, bits int, f func(old V, exists bool) (V, bool)) *Radix[K, V] {
+	x, err := r.TryUpdate(n, bits, f)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// TryUpdate is like Update, but returns an error instead of panicking. The errors
+// are the same as for TryInsert, f is not called when an error is returned.
+func (r *Radix[K, V]) TryUpdate(n K, bits int, f func(old V, exists bool) (V, bool)) (*Radix[K, V], error) {
+	if err := r.check(n, bits, true); err != nil {
+		return nil, err
+	}
+	return r.update(n, bits, f), nil
+}
+
+// Find searches the tree for the key n, where the first bits bits of n
+// are significant. It returns the node holding exactly n/bits when it exists,
+// otherwise the node with the longest prefix that contains n/bits, i.e. a node
+// with fewer bits whose key matches n on those bits. Nodes with more than bits
+// bits are never returned. It returns nil when nothing can be found. Find panics
+// with ErrNotRoot or ErrInvalidPrefixLen, bits of n beyond bits are ignored.
+func (r *Radix[K, V]) Find(n K, bits int) *Radix[K, V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	return r.find(n, bits, nil)
+}
+
+// Get returns the node holding exactly the key n with bits significant bits,
+// or nil when that prefix is not in the tree. r must be the root of the tree.
+// Get panics like Find.
+func (r *Radix[K, V]) Get(n K, bits int) *Radix[K, V] {
+	if err := r.check(n, bits, false); err != nil {
+		panic(err)
+	}
+	if x := r.find(n, bits, nil); x != nil && x.bits == bits {
+		return x
+	}
+	return nil
+}
+
+// LongestMatch returns the node with the longest prefix that contains the host
+// address n, together with the length of that prefix. When no prefix contains
+// n, nil and 0 are returned. r must be the root of the tree.
+func (r *Radix[K, V]) LongestMatch(n K) (*Radix[K, V], int) {
+	if r.parent != nil {
+		panic(ErrNotRoot)
+	}
+	x := r.find(n, bitSize[K](), nil)
+	if x == nil {
+		return nil, 0
+	}
+	return x, x.bits
+}
+
+// Do traverses the tree r in breadth-first order. For each visited node,
+// the function f is called with the current node, and the branch taken
+// (0 for the zero, 1 for the one branch, -1 is used for the root node).
+func (r *Radix[K, V]) Do(f func(*Radix[K, V], int)) {
+	q := make(queue[K, V], 0)
+
+	q.Push(&node[K, V]{r, -1})
+	x := q.Pop()
+	for x != nil {
+		f(x.Radix, x.branch)
+		for i, b := range x.Radix.branch {
+			if b != nil {
+				q.Push(&node[K, V]{b, i})
+			}
+		}
+		x = q.Pop()
+	}
+}
+
+// check validates the arguments of the exported methods, when host is true
+// n may not have bits set beyond the first bits bits.
+func (r *Radix[K, V]) check(n K, bits int, host bool) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	return checkPrefix(n, bits, host)
+}
+
+// checkPrefix validates n/bits, when host is true n may not have bits set
+// beyond the first bits bits.
+func checkPrefix[K Key](n K, bits int, host bool) error {
+	if bits < 1 || bits > bitSize[K]() {
+		return ErrInvalidPrefixLen
+	}
+	if host && hostBits(n, bits) {
+		return ErrHostBitsSet
+	}
+	return nil
+}
+
+// Implement insert, the prefix of r contains n/bits.
+func (r *Radix[K, V]) insert(n K, bits int, v V) *Radix[K, V] {
+	if r.skip == bits { // I should be put here
+		r.set(n, bits, v)
+		return r
+	}
+	k := bitK(n, bitSize[K]()-1-r.skip)
+	c := r.branch[k]
+	if c == nil {
+		r.branch[k] = r.new(n, bits)
+		r.branch[k].set(n, bits, v)
+		return r.branch[k]
+	}
+	l := common(c.key, n, min(c.skip, bits))
+	if l == c.skip {
+		// c covers n/bits, continue down
+		return c.insert(n, bits, v)
+	}
+	// n/bits is shorter than c, or diverges from it, put a node in between
+	// that covers both
+	g := r.new(n, l)
+	g.attach(c)
+	r.branch[k] = g
+	return g.insert(n, bits, v)
+}
+
+// Walk down to the last node whose prefix contains n/bits, and update, insert or
+// remove n/bits from there.
+func (r *Radix[K, V]) update(n K, bits int, f func(V, bool) (V, bool)) *Radix[K, V] {
+	x := r
+	for x.skip < bits {
+		c := x.branch[bitK(n, bitSize[K]()-1-x.skip)]
+		if c == nil || c.skip > bits || !match(c.key, n, c.skip) {
+			break
+		}
+		x = c
+	}
+	if x.bits == bits {
+		v, keep := f(x.Value, true)
+		if keep {
+			x.Value = v
+			return x
+		}
+		x.clear()
+		x.compact()
+		return nil
+	}
+	var zero V
+	v, keep := f(zero, false)
+	if !keep {
+		return nil
+	}
+	return x.insert(n, bits, v)
+}
+
+// Walk the tree searching for n, when found remove the key from its node and
+// compact the tree.
+func (r *Radix[K, V]) remove(n K, bits int) *Radix[K, V] {
+	x := r.find(n, bits, nil)
+	if x == nil || x.bits != bits {
+		return nil
+	}
+	// save x in r1
+	r1 := &Radix[K, V]{key: x.key, skip: x.bits, bits: x.bits, Value: x.Value}
+	x.clear()
+	x.compact()
+	return r1
+}
+
+// Compact the tree, when r does not hold a key and has less than two branches
+// it is removed and its branch, if any, takes its place. The root is never removed.
+func (r *Radix[K, V]) compact() {
+	if r.parent == nil || r.bits != 0 {
+		// fun stops
+		return
+	}
+	p := r.parent
+	switch {
+	case r.branch[0] != nil && r.branch[1] != nil:
+		return
+	case r.branch[0] != nil:
+		p.replace(r, r.branch[0])
+	case r.branch[1] != nil:
+		p.replace(r, r.branch[1])
+	default:
+		// kill that branch, p may now be left with one branch
+		p.replace(r, nil)
+		p.compact()
+	}
+}
+
+// find walks the path of n down to bits bits, and keeps the last matching node
+// that has a key in tow. The keys skipped by nodes without a key are not checked:
+// when they do not match, neither do the keys of the nodes below them.
+func (r *Radix[K, V]) find(n K, bits int, last *Radix[K, V]) *Radix[K, V] {
+	for x := r; x != nil && x.skip <= bits; {
+		if x.bits > 0 {
+			if !match(x.key, n, x.bits) {
+				break
+			}
+			if x.bits == bits {
+				// our key
+				return x
+			}
+			last = x
+		}
+		if x.skip == bits {
+			break
+		}
+		x = x.branch[bitK(n, bitSize[K]()-1-x.skip)]
+	}
+	return last
+}
+
+// Return a new node covering the first skip bits of key, with r as its parent
+func (r *Radix[K, V]) new(key K, skip int) *Radix[K, V] {
+	return &Radix[K, V]{parent: r, key: maskKey(key, skip), skip: skip}
+}
+
+// attach makes c a branch of r.
+func (r *Radix[K, V]) attach(c *Radix[K, V]) {
+	c.parent = r
+	r.branch[bitK(c.key, bitSize[K]()-1-r.skip)] = c
+}
+
+// replace replaces the branch c of r with d, d may be nil.
+func (r *Radix[K, V]) replace(c, d *Radix[K, V]) {
+	for i := range r.branch {
+		if r.branch[i] == c {
+			r.branch[i] = d
+		}
+	}
+	if d != nil {
+		d.parent = r
+	}
+}
+
+func (r *Radix[K, V]) set(key K, bits int, value V) {
+	r.key = key
+	r.bits = bits
+	r.Value = value
+}
+
+// clear removes the key from r, the prefix r covers stays the same.
+func (r *Radix[K, V]) clear() {
+	var v V
+	r.bits = 0
+	r.Value = v
+}
+
+// adopt replaces the contents of the root r with the tree x, x must not be used afterwards.
+func (r *Radix[K, V]) adopt(x *Radix[K, V]) {
+	*r = *x
+	for _, b := range r.branch {
+		if b != nil {
+			b.parent = r
+		}
+	}
+}
+
+// bitSize returns the number of bits in K.
+func bitSize[K Key]() int {
+	var k K
+	return int(unsafe.Sizeof(k)) * 8
+}
+
+// words returns n as a 128 bits number, split in the upper and lower 64 bits.
+func words[K Key](n K) (hi, lo uint64) {
+	p := unsafe.Pointer(&n)
+	switch unsafe.Sizeof(n) {
+	case 1:
+		return 0, uint64(*(*uint8)(p))
+	case 2:
+		return 0, uint64(*(*uint16)(p))
+	case 4:
+		return 0, uint64(*(*uint32)(p))
+	case 8:
+		return 0, *(*uint64)(p)
+	}
+	u := *(*Uint128)(p)
+	return u.Hi, u.Lo
+}
+
+// fromWords returns the 128 bits number hi, lo as a K, bits that do not fit in K are lost.
+func fromWords[K Key](hi, lo uint64) (n K) {
+	p := unsafe.Pointer(&n)
+	switch unsafe.Sizeof(n) {
+	case 1:
+		*(*uint8)(p) = uint8(lo)
+	case 2:
+		*(*uint16)(p) = uint16(lo)
+	case 4:
+		*(*uint32)(p) = uint32(lo)
+	case 8:
+		*(*uint64)(p) = lo
+	default:
+		*(*Uint128)(p) = Uint128{hi, lo}
+	}
+	return n
+}
+
+// maskKey returns n with all bits beyond the first bits bits cleared.
+func maskKey[K Key](n K, bits int) K {
+	hi, lo := words(n)
+	mhi, mlo := mask128(128 - bitSize[K]() + bits)
+	return fromWords[K](hi&mhi, lo&mlo)
+}
+
+// common returns the number of leading bits a and b have in common, at most max.
+func common[K Key](a, b K, max int) int {
+	ahi, alo := words(a)
+	bhi, blo := words(b)
+	l := 64 + bits.LeadingZeros64(alo^blo)
+	if ahi != bhi {
+		l = bits.LeadingZeros64(ahi ^ bhi)
+	}
+	return min(l-(128-bitSize[K]()), max)
+}
+
+// match returns true when the first bits bits of a and b are equal.
+func match[K Key](a, b K, bits int) bool {
+	return bits <= 0 || common(a, b, bits) == bits
+}
+
+// hostBits returns true when n has bits set beyond the first bits bits.
+func hostBits[K Key](n K, bits int) bool {
+	hi, lo := words(n)
+	mhi, mlo := mask128(128 - bitSize[K]() + bits)
+	return hi&^mhi != 0 || lo&^mlo != 0
+}
+
+// From: http://stackoverflow.com/questions/2249731/how-to-get-bit-by-bit-data-from-a-integer-value-in-c
+
+// Return bit k from n. We count from the right, MSB left.
+// So k = 0 is the last bit on the left and k = 31 is the first bit on the right
+// for a 32 bits key.
+func bitK[K Key](n K, k int) byte {
+	hi, lo := words(n)
+	if k >= 64 {
+		lo, k = hi, k-64
+	}
+	return byte((lo >> uint(k)) & 1)
+}
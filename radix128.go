@@ -0,0 +1,18 @@
+package bitradix
+
+// Radix128 implements a radix tree with an Uint128 as its key.
+type Radix128 = Radix[Uint128, interface{}]
+
+// Radix128Of implements a radix tree with an Uint128 as its key and
+// values of type V.
+type Radix128Of[V any] = Radix[Uint128, V]
+
+// New128 returns an empty, initialized Radix128 tree.
+func New128() *Radix128 {
+	return New[Uint128, interface{}]()
+}
+
+// New128Of returns an empty, initialized Radix128Of tree.
+func New128Of[V any]() *Radix128Of[V] {
+	return New[Uint128, V]()
+}
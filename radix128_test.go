@@ -0,0 +1,137 @@
+package bitradix
+
+import (
+	"net"
+	"testing"
+)
+
+func ipToUint128(t *testing.T, n *net.IPNet) (i Uint128, mask int) {
+	ip := n.IP.To16()
+	for _, b := range ip[:8] {
+		i.Hi = i.Hi<<8 | uint64(b)
+	}
+	for _, b := range ip[8:] {
+		i.Lo = i.Lo<<8 | uint64(b)
+	}
+	mask, _ = n.Mask.Size()
+	return
+}
+
+func addRoute128(t *testing.T, r *Radix128, s string, asn uint32) {
+	_, ipnet, _ := net.ParseCIDR(s)
+	net, mask := ipToUint128(t, ipnet)
+	t.Logf("Route %s (%064b %064b), AS %d\n", s, net.Hi, net.Lo, asn)
+	r.Insert(net, mask, asn)
+	validate(t, r)
+}
+
+func findRoute128(t *testing.T, r *Radix128, s string) interface{} {
+	_, ipnet, _ := net.ParseCIDR(s)
+	net, mask := ipToUint128(t, ipnet)
+	t.Logf("Search %28s %064b %064b/%d\n", s, net.Hi, net.Lo, mask)
+	node := r.Find(net, mask)
+	if node == nil {
+		return uint32(0)
+	}
+	return node.Value
+}
+
+func TestBitK128(t *testing.T) {
+	tests := map[bittest64]byte{
+		bittest64{0x1, 0}:   1,
+		bittest64{0x1, 64}:  1,
+		bittest64{0x1, 65}:  0,
+		bittest64{0x40, 70}: 1,
+		bittest64{0x40, 6}:  0,
+	}
+	for test, expected := range tests {
+		if x := bitK(Uint128{test.key, 0x1}, test.bit); x != expected {
+			t.Logf("Expected %d for %064b (bit #%d), got %d\n", expected, test.key, test.bit, x)
+			t.Fail()
+		}
+	}
+}
+
+func TestFindIP128(t *testing.T) {
+	r := New128()
+	// not a map to have influence on the order
+	addRoute128(t, r, "2001:db8::/32", 10)
+	addRoute128(t, r, "2001:db8:21::/48", 21)
+	addRoute128(t, r, "2001:db8:21:1::/64", 211)
+	addRoute128(t, r, "2001:db8:21:1::8000:0/97", 2111)
+	addRoute128(t, r, "2001:db8:21:1::1/128", 2112)
+	addRoute128(t, r, "64:ff9b::/96", 64)
+
+	r.Do(func(r1 *Radix128, i int) {
+		t.Logf("(%2d): %064b %064b/%d -> %d\n", i, r1.key.Hi, r1.key.Lo, r1.bits, r1.Value)
+	})
+	testips := map[string]uint32{
+		"2001:db8:20:1::/64":          10,
+		"2001:db8:21:2::1/128":        21,
+		"2001:db8:21:1::2/128":        211,
+		"2001:db8:21:1::1/128":        2112,
+		"2001:db8:21:1::8000:1/128":   2111,
+		"2001:db8:21:1:1::8000:1/128": 211,
+		"64:ff9b::10.0.0.1/128":       64,
+		"64:ff9b:1::10.0.0.1/128":     0,
+		"3ffe::/64":                   0,
+	}
+
+	for ip, asn := range testips {
+		if x := findRoute128(t, r, ip); asn != x {
+			t.Logf("Expected %d, got %d for %s\n", asn, x, ip)
+			t.Fail()
+		}
+	}
+}
+
+func TestFindMySelf128(t *testing.T) {
+	r := New128()
+	routes := map[string]uint32{
+		"2001:db8::/32":              4694,
+		"2001:db8:1::/48":            2554,
+		"2001:db8:1:1::/64":          2516,
+		"2001:db8:1:1::/96":          2517,
+		"2001:db8:1:1::1/128":        2518,
+		"2001:db8:1:1::2/127":        2519,
+		"2001:db8:1:1:8000::/65":     2520,
+		"2001:db8:1:1:8000::1/128":   2521,
+		"64:ff9b::/96":               64,
+		"2a02:1::/36":                1001,
+		"2a02:1:8000::ffff:0:0/96":   1002,
+		"2a02:1:8000::ffff:0:ff/128": 1003,
+	}
+	for ip, asn := range routes {
+		addRoute128(t, r, ip, asn)
+	}
+	fail := false
+	for ip, asn := range routes {
+		if x := findRoute128(t, r, ip); asn != x {
+			t.Logf("Expected %d, got %d for %s\n", asn, x, ip)
+			fail = true
+			t.Fail()
+		}
+	}
+	if fail {
+		r.Do(func(r1 *Radix128, i int) {
+			t.Logf("(%2d): %064b %064b/%d -> %d\n", i, r1.key.Hi, r1.key.Lo, r1.bits, r1.Value)
+		})
+	}
+}
+
+func TestRemove128(t *testing.T) {
+	r := New128()
+	addRoute128(t, r, "2001:db8::/32", 10)
+	addRoute128(t, r, "2001:db8::1/128", 11)
+	_, ipnet, _ := net.ParseCIDR("2001:db8::1/128")
+	k, bits := ipToUint128(t, ipnet)
+	if x := r.Remove(k, bits); x == nil || x.Value != uint32(11) {
+		t.Logf("Expected %d, got %v\n", 11, x)
+		t.Fail()
+	}
+	validate(t, r)
+	if x := findRoute128(t, r, "2001:db8::1/128"); x != uint32(10) {
+		t.Logf("Expected %d, got %d\n", 10, x)
+		t.Fail()
+	}
+}
@@ -0,0 +1,303 @@
+package bitradix
+
+import (
+	"net"
+	"testing"
+)
+
+type bittest64 struct {
+	key uint64
+	bit int
+}
+
+var tests64 = map[uint64]uint32{
+	0x8000000000000000: 2012,
+	0x4000000000000000: 2010,
+	0x9000000000000000: 2013,
+}
+
+const bits64 = 5
+
+func newTree64(t *testing.T) *Radix64 {
+	r := New64()
+	for k, v := range tests64 {
+		r.Insert(k, bits64, v)
+		validate(t, r)
+	}
+	return r
+}
+
+func TestInsert64(t *testing.T) {
+	tests := map[bittest64]uint32{
+		bittest64{0x8100000000000000, 9}:  2012,
+		bittest64{0x8000000000000000, 2}:  2013,
+		bittest64{0x8100000080000000, 33}: 2014,
+	}
+	r := New64()
+	for bits, value := range tests {
+		t.Logf("Inserting %064b/%d\n", bits.key, bits.bit)
+		if x := r.Insert(bits.key, bits.bit, value); x.Value != value {
+			t.Logf("Expected %d, got %d for %d (node type %v)\n", value, x.Value, bits.key, x.Leaf())
+			t.Fail()
+		}
+		validate(t, r)
+		t.Logf("Tree\n")
+		r.Do(func(r1 *Radix64, i int) { t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
+	}
+}
+
+func TestInsert264(t *testing.T) {
+	tests := map[bittest64]uint32{
+		bittest64{0x8100000000000000, 9}: 2012,
+		bittest64{0xA000000000000000, 4}: 1000,
+		bittest64{0x8000000000000000, 2}: 2013,
+	}
+	r := New64()
+	for bits, value := range tests {
+		t.Logf("Inserting %064b/%d\n", bits.key, bits.bit)
+		if x := r.Insert(bits.key, bits.bit, value); x.Value != value {
+			t.Logf("Expected %d, got %d for %d (node type %v)\n", value, x.Value, bits.key, x.Leaf())
+			t.Fail()
+		}
+		validate(t, r)
+		t.Logf("Tree\n")
+		r.Do(func(r1 *Radix64, i int) { t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
+	}
+}
+
+func TestInsertIdempotent64(t *testing.T) {
+	r := New64()
+	r.Insert(0x8000000000000000, bits64, 2012)
+	validate(t, r)
+	r.Insert(0x8000000000000000, bits64, 2013)
+	validate(t, r)
+	r.Do(func(r1 *Radix64, i int) { t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
+	if x := r.Find(0x8000000000000000, bits64); x.Value != 2013 {
+		t.Logf("Expected %d, got %d for %d\n", 2013, x.Value, 0x08)
+		t.Fail()
+	}
+}
+
+func TestFindExact64(t *testing.T) {
+	r := newTree64(t)
+	r.Do(func(r1 *Radix64, i int) { t.Logf("%p (%2d): %064b/%d -> %d\n", r1, i, r1.key, r1.bits, r1.Value) })
+	for k, v := range tests64 {
+		x := r.Find(k, bits64)
+		if x == nil {
+			t.Logf("Got nil for %064b\n", k)
+			t.Fail()
+			continue
+		}
+		if x.Value != v {
+			t.Logf("Expected %d, got %d for %064b (node type %v)\n", v, x.Value, k, x.Leaf())
+			t.Fail()
+		}
+	}
+}
+
+func TestRemove64(t *testing.T) {
+	r := newTree64(t)
+	for _, k := range []uint64{0x4000000000000000, 0x8000000000000000, 0x9000000000000000} {
+		t.Logf("Tree after removal of %064b/%d\n", k, bits64)
+		if x := r.Remove(k, bits64); x == nil || x.Value != tests64[k] {
+			t.Logf("Expected %d, got %v\n", tests64[k], x)
+			t.Fail()
+		}
+		validate(t, r)
+		r.Do(func(r1 *Radix64, i int) {
+			t.Logf("[%010p %010p] (%2d): %064b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
+		})
+		if x := r.Find(k, bits64); x != nil {
+			t.Logf("Expected nil after removal, got %d\n", x.Value)
+			t.Fail()
+		}
+	}
+}
+
+// Insert one value and remove it again
+func TestRemove264(t *testing.T) {
+	r := New64()
+	k, v := uint64(0x2001db8000100000), uint32(2013)
+	r.Insert(k, 44, v)
+	validate(t, r)
+	r.Do(func(r1 *Radix64, i int) {
+		t.Logf("[%010p %010p] (%2d): %064b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
+	})
+	if x := r.Remove(k, 44); x == nil || x.Value != v {
+		t.Logf("Expected %d, got %v\n", v, x)
+		t.Fail()
+	}
+	validate(t, r)
+	r.Do(func(r1 *Radix64, i int) {
+		t.Logf("[%010p %010p] (%2d): %064b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
+	})
+	if x := r.Find(k, 44); x != nil {
+		t.Logf("Expected nil after removal, got %d\n", x.Value)
+		t.Fail()
+	}
+}
+
+// Test with "real-life" IPv6 addresses, only the upper 64 bits are used.
+func ipToUint64(t *testing.T, n *net.IPNet) (i uint64, mask int) {
+	ip := n.IP.To16()
+	for _, b := range ip[:8] {
+		i = i<<8 | uint64(b)
+	}
+	mask, _ = n.Mask.Size()
+	return
+}
+
+func addRoute64(t *testing.T, r *Radix64, s string, asn uint32) {
+	_, ipnet, _ := net.ParseCIDR(s)
+	net, mask := ipToUint64(t, ipnet)
+	t.Logf("Route %s (%064b), AS %d\n", s, net, asn)
+	r.Insert(net, mask, asn)
+	validate(t, r)
+}
+
+func findRoute64(t *testing.T, r *Radix64, s string) interface{} {
+	_, ipnet, _ := net.ParseCIDR(s)
+	net, mask := ipToUint64(t, ipnet)
+	t.Logf("Search %24s %064b/%d\n", s, net, mask)
+	node := r.Find(net, mask)
+	if node == nil {
+		return uint32(0)
+	}
+	return node.Value
+}
+
+func TestFindIP64(t *testing.T) {
+	r := New64()
+	// not a map to have influence on the order
+	addRoute64(t, r, "2001:db8::/32", 10)
+	addRoute64(t, r, "2001:db8:20::/44", 20)
+	addRoute64(t, r, "2001:db8:21::/48", 21)
+	addRoute64(t, r, "2001:db8:21:1::/64", 211)
+	addRoute64(t, r, "2a00:1450::/32", 15169)
+	addRoute64(t, r, "2a00:1450:4001:800::/56", 15170)
+
+	r.Do(func(r1 *Radix64, i int) {
+		t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value)
+	})
+	testips := map[string]uint32{
+		"2001:db8:20:1::/64":      20,
+		"2001:db8:22:1::/64":      20,
+		"2001:db8:1f:1::/64":      10,
+		"2001:db8:21:2::/64":      21,
+		"2001:db8:21:1::/64":      211,
+		"2a00:1450:4001:801::/64": 15170,
+		"2a00:1450:4001:900::/64": 15169,
+		"3ffe::/64":               0,
+	}
+
+	for ip, asn := range testips {
+		if x := findRoute64(t, r, ip); asn != x {
+			t.Logf("Expected %d, got %d for %s\n", asn, x, ip)
+			t.Fail()
+		}
+	}
+}
+
+// findMySelfRoutes64 are the IPv6 equivalent of findMySelfRoutes.
+var findMySelfRoutes64 = map[string]uint32{
+	"2001:db8::/32":           4694,
+	"2001:db8:1::/48":         2554,
+	"2001:db8:1:1::/64":       2516,
+	"2001:db8:1:2::/64":       2516,
+	"2001:db8:2::/48":         4716,
+	"2001:db8:8000::/33":      4725,
+	"2001:db8:8000:1000::/52": 4725,
+	"2001:db9::/32":           4759,
+	"2001:db9:ff::/48":        4759,
+	"2001:db9:ff:ff::/64":     7672,
+	"2001:db9:ff:fe::/63":     7668,
+	"2001:db9:ff:fc::/62":     7663,
+	"2a02:1::/36":             1001,
+	"2a02:1:8000::/40":        1001,
+}
+
+func TestFindMySelf64(t *testing.T) {
+	r := New64()
+	for ip, asn := range findMySelfRoutes64 {
+		addRoute64(t, r, ip, asn)
+	}
+	fail := false
+	for ip, asn := range findMySelfRoutes64 {
+		if x := findRoute64(t, r, ip); asn != x {
+			t.Logf("Expected %d, got %d for %s\n", asn, x, ip)
+			fail = true
+			t.Fail()
+		}
+	}
+	if fail {
+		r.Do(func(r1 *Radix64, i int) {
+			t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value)
+		})
+	}
+}
+
+// findOverwriteRoutes64 are the IPv6 equivalent of findOverwriteRoutes.
+var findOverwriteRoutes64 = map[string]uint32{
+	"2001:db8:0:14::/63": 2518,
+	"2001:db8:0:16::/63": 2519,
+	"2001:db8:0:18::/63": 2520,
+	"2001:db8:0:1c::/62": 2517,
+	"2001:db8:0:40::/58": 18144,
+}
+
+func TestFindOverwrite64(t *testing.T) {
+	r := New64()
+	for ip, asn := range findOverwriteRoutes64 {
+		addRoute64(t, r, ip, asn)
+	}
+	r.Do(func(r1 *Radix64, i int) {
+		t.Logf("(%2d): %064b/%d -> %d\n", i, r1.key, r1.bits, r1.Value)
+	})
+
+	for ip, asn := range findOverwriteRoutes64 {
+		x := findRoute64(t, r, ip)
+		if x != asn {
+			t.Logf("Expected %d, got %d\n", asn, x)
+			t.Fail()
+		}
+	}
+}
+
+func TestBitK64(t *testing.T) {
+	tests := map[bittest64]byte{
+		bittest64{0x40, 0}:                0,
+		bittest64{0x40, 6}:                1,
+		bittest64{0x8000000000000000, 63}: 1,
+		bittest64{0x100000000, 32}:        1,
+	}
+	for test, expected := range tests {
+		if x := bitK(test.key, test.bit); x != expected {
+			t.Logf("Expected %d for %064b (bit #%d), got %d\n", expected, test.key, test.bit, x)
+			t.Fail()
+		}
+	}
+}
+
+func TestQueue64(t *testing.T) {
+	q := make(queue[uint64, interface{}], 0)
+	tests := []uint32{20, 30, 40}
+	for _, val := range tests {
+		q.Push(&node[uint64, interface{}]{&Radix64{Value: val}, -1})
+	}
+	for _, val := range tests {
+		x := q.Pop()
+		if x == nil {
+			t.Logf("Expected non-nil, got nil\n")
+			t.Fail()
+			continue
+		}
+		if x.Radix.Value != val {
+			t.Logf("Expected %d, got %d\n", val, x.Radix.Value)
+			t.Fail()
+		}
+	}
+	if x := q.Pop(); x != nil {
+		t.Logf("Expected nil, got %d\n", x.Radix.Value)
+		t.Fail()
+	}
+}
@@ -19,10 +19,11 @@ var tests = map[uint32]uint32{
 
 const bits32 = 5
 
-func newTree32() *Radix32 {
+func newTree32(t *testing.T) *Radix32 {
 	r := New32()
 	for k, v := range tests {
 		r.Insert(k, bits32, v)
+		validate(t, r)
 	}
 	return r
 }
@@ -39,6 +40,7 @@ func TestInsert(t *testing.T) {
 			t.Logf("Expected %d, got %d for %d (node type %v)\n", value, x.Value, bits.key, x.Leaf())
 			t.Fail()
 		}
+		validate(t, r)
 		t.Logf("Tree\n")
 		r.Do(func(r1 *Radix32, i int) { t.Logf("(%2d): %032b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
 	}
@@ -57,17 +59,19 @@ func TestInsert2(t *testing.T) {
 			t.Logf("Expected %d, got %d for %d (node type %v)\n", value, x.Value, bits.key, x.Leaf())
 			t.Fail()
 		}
-		t.Logf("Tree\n")
-		r.Do(func(r1 *Radix32, i int) { t.Logf("(%2d): %032b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
+		validate(t, r)
+		t.Logf("Tree\n%s", r)
 	}
 }
 
 func TestInsertIdempotent(t *testing.T) {
 	r := New32()
 	r.Insert(0x80000000, bits32, 2012)
+	validate(t, r)
 	t.Logf("Tree\n")
 	r.Do(func(r1 *Radix32, i int) { t.Logf("(%2d): %032b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
 	r.Insert(0x80000000, bits32, 2013)
+	validate(t, r)
 	t.Logf("Tree\n")
 	r.Do(func(r1 *Radix32, i int) { t.Logf("(%2d): %032b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
 	if x := r.Find(0x80000000, bits32); x.Value != 2013 {
@@ -86,6 +90,7 @@ func TestFindExact(t *testing.T) {
 	for k, v := range tests {
 		t.Logf("Tree after insert of %032b (%x %d)\n", k, k, k)
 		r.Insert(k, bits32, v)
+		validate(t, r)
 		r.Do(func(r1 *Radix32, i int) { t.Logf("%p (%2d): %032b/%d -> %d\n", r1, i, r1.key, r1.bits, r1.Value) })
 	}
 	for k, v := range tests {
@@ -103,7 +108,7 @@ func TestFindExact(t *testing.T) {
 }
 
 func TestRemove(t *testing.T) {
-	r := newTree32()
+	r := newTree32(t)
 	t.Logf("Tree complete\n")
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("[%010p %010p] (%2d): %032b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
@@ -111,18 +116,21 @@ func TestRemove(t *testing.T) {
 	k, v := uint32(0x40000000), uint32(2010)
 	t.Logf("Tree after removal of %032b/%d %d (%x %d)\n", k, bits32, v, k, k)
 	r.Remove(k, bits32)
+	validate(t, r)
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("[%010p %010p] (%2d): %032b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
 	})
 	k, v = uint32(0x80000000), uint32(2012)
 	t.Logf("Tree after removal of %032b/%d %d (%x %d)\n", k, bits32, v, k, k)
 	r.Remove(k, bits32)
+	validate(t, r)
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("[%010p %010p] (%2d): %032b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
 	})
 	k, v = uint32(0x90000000), uint32(2013)
 	t.Logf("Tree after removal of %032b/%d %d (%x %d)\n", k, bits32, v, k, k)
 	r.Remove(k, bits32)
+	validate(t, r)
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("[%010p %010p] (%2d): %032b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
 	})
@@ -137,6 +145,7 @@ func TestRemove2(t *testing.T) {
 	})
 	k, v := uint32(0x90000000), uint32(2013)
 	r.Insert(k, bits32, v)
+	validate(t, r)
 
 	t.Logf("Tree complete\n")
 	r.Do(func(r1 *Radix32, i int) {
@@ -144,6 +153,7 @@ func TestRemove2(t *testing.T) {
 	})
 	t.Logf("Tree after removal of %032b/%d %d (%x %d)\n", k, bits32, v, k, k)
 	r.Remove(k, bits32)
+	validate(t, r)
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("[%010p %010p] (%2d): %032b/%d -> %d\n", r1.branch[0], r1.branch[1], i, r1.key, r1.bits, r1.Value)
 	})
@@ -167,6 +177,7 @@ func addRoute(t *testing.T, r *Radix32, s string, asn uint32) {
 	net, mask := ipToUint(t, ipnet)
 	t.Logf("Route %s (%032b), AS %d\n", s, net, asn)
 	r.Insert(net, mask, asn)
+	validate(t, r)
 }
 
 func findRoute(t *testing.T, r *Radix32, s string) interface{} {
@@ -180,6 +191,14 @@ func findRoute(t *testing.T, r *Radix32, s string) interface{} {
 	return node.Value
 }
 
+// validate fails the test when the tree r is not valid.
+func validate[K Key, V any](t *testing.T, r *Radix[K, V]) {
+	if err := r.Validate(); err != nil {
+		t.Logf("Invalid tree: %s\n%s", err, r)
+		t.Fail()
+	}
+}
+
 func TestFindIP(t *testing.T) {
 	r := New32()
 	// not a map to have influence on the order
@@ -221,7 +240,6 @@ func TestFindIP(t *testing.T) {
 func TestFindIPShort(t *testing.T) {
 	r := New32()
 	// not a map to have influence on the inserting order
-	// The /14 will overwrite the /10 ...
 	addRoute(t, r, "10.0.0.2/8", 10)
 	addRoute(t, r, "10.0.0.0/14", 11)
 	addRoute(t, r, "10.20.0.0/14", 20)
@@ -248,11 +266,11 @@ func TestFindIPShort(t *testing.T) {
 	addRoute(t, r, "210.166.209.0/24", 7663)
 	addRoute(t, r, "210.166.211.0/24", 7663)
 
-	r.Do(func(r1 *Radix32, i int) { t.Logf("(%2d): %032b/%d -> %d\n", i, r1.key, r1.bits, r1.Value) })
+	t.Logf("Tree\n%s", r)
 
 	testips := map[string]uint32{
 		"10.20.1.2/32":     20,
-		"10.19.0.1/32":     0, // because 10.0.0.2/8 isn't there this return 0
+		"10.19.0.1/32":     10, // the /14 does not overwrite the /8
 		"10.0.0.2/32":      11,
 		"10.1.0.1/32":      11,
 		"210.169.0.0/17":   2516,
@@ -278,39 +296,41 @@ func TestFindIPShort(t *testing.T) {
 	}
 }
 
+// findMySelfRoutes used to lose keys depending on the order of insertion.
+var findMySelfRoutes = map[string]uint32{
+	"210.168.0.0/17":   4694,
+	"210.168.96.0/19":  2554,
+	"210.168.192.0/18": 2516,
+	"210.169.0.0/17":   2516,
+	"210.168.128.0/18": 4716,
+	"210.169.128.0/17": 4725,
+	"210.169.212.0/24": 4725,
+	"210.16.14.0/24":   4759,
+	"210.16.0.0/24":    4759,
+	"210.16.1.0/24":    4759,
+	"210.16.40.0/24":   4759,
+	"210.166.0.0/19":   7672,
+	"210.166.5.0/24":   7668,
+	"210.167.0.0/19":   7668,
+	"210.166.0.0/20":   7672,
+	"210.166.96.0/19":  4693,
+	"210.167.112.0/20": 4685,
+	"210.167.128.0/18": 4716,
+	"210.167.192.0/18": 4716,
+	"210.167.32.0/19":  7663,
+	"210.166.209.0/24": 7663,
+	"210.166.211.0/24": 7663,
+	"87.71.192.0/18":   1001,
+	"87.71.128.0/18":   1001,
+}
+
 func TestFindMySelf(t *testing.T) {
 	r := New32()
-	routes := map[string]uint32{
-		"210.168.0.0/17":   4694,
-		"210.168.96.0/19":  2554,
-		"210.168.192.0/18": 2516,
-		"210.169.0.0/17":   2516,
-		"210.168.128.0/18": 4716,
-		"210.169.128.0/17": 4725,
-		"210.169.212.0/24": 4725,
-		"210.16.14.0/24":   4759,
-		"210.16.0.0/24":    4759,
-		"210.16.1.0/24":    4759,
-		"210.16.40.0/24":   4759,
-		"210.166.0.0/19":   7672,
-		"210.166.5.0/24":   7668,
-		"210.167.0.0/19":   7668,
-		"210.166.0.0/20":   7672,
-		"210.166.96.0/19":  4693,
-		"210.167.112.0/20": 4685,
-		"210.167.128.0/18": 4716,
-		"210.167.192.0/18": 4716,
-		"210.167.32.0/19":  7663,
-		"210.166.209.0/24": 7663,
-		"210.166.211.0/24": 7663,
-		"87.71.192.0/18":   1001,
-		"87.71.128.0/18":   1001,
-	}
-	for ip, asn := range routes {
+	for ip, asn := range findMySelfRoutes {
 		addRoute(t, r, ip, asn)
 	}
 	fail := false
-	for ip, asn := range routes {
+	for ip, asn := range findMySelfRoutes {
 		if x := findRoute(t, r, ip); asn != x {
 			t.Logf("Expected %d, got %d for %s\n", asn, x, ip)
 			fail = true
@@ -324,23 +344,25 @@ func TestFindMySelf(t *testing.T) {
 	}
 }
 
+// findOverwriteRoutes used to overwrite each other.
+var findOverwriteRoutes = map[string]uint32{
+	"1.0.20.0/23": 2518,
+	"1.0.22.0/23": 2519,
+	"1.0.24.0/23": 2520,
+	"1.0.28.0/22": 2517,
+	"1.0.64.0/18": 18144,
+}
+
 func TestFindOverwrite(t *testing.T) {
 	r := New32()
-	routes := map[string]uint32{
-		"1.0.20.0/23": 2518,
-		"1.0.22.0/23": 2519,
-		"1.0.24.0/23": 2520,
-		"1.0.28.0/22": 2517,
-		"1.0.64.0/18": 18144,
-	}
-	for ip, asn := range routes {
+	for ip, asn := range findOverwriteRoutes {
 		addRoute(t, r, ip, asn)
 	}
 	r.Do(func(r1 *Radix32, i int) {
 		t.Logf("(%2d): %032b/%d %s -> %d\n", i, r1.key, r1.bits, uintToIP(r1.key), r1.Value)
 	})
 
-	for ip, asn := range routes {
+	for ip, asn := range findOverwriteRoutes {
 		x := findRoute(t, r, ip)
 		if x == nil {
 			t.Logf("Expected %d, got nil\n", asn)
@@ -359,7 +381,7 @@ func TestBitK32(t *testing.T) {
 		bittest{0x40, 6}: 1,
 	}
 	for test, expected := range tests {
-		if x := bitK32(test.key, test.bit); x != expected {
+		if x := bitK(test.key, test.bit); x != expected {
 			t.Logf("Expected %d for %032b (bit #%d), got %d\n", expected, test.key, test.bit, x)
 			t.Fail()
 		}
@@ -367,11 +389,11 @@ func TestBitK32(t *testing.T) {
 }
 
 func TestQueue(t *testing.T) {
-	q := make(queue32, 0)
+	q := make(queue[uint32, interface{}], 0)
 	r := New32()
 	r.Value = 10
 
-	q.Push(&node32{r, -1})
+	q.Push(&node[uint32, interface{}]{r, -1})
 	if r1 := q.Pop(); r1.Value != 10 {
 		t.Logf("Expected %d, got %d\n", 10, r.Value)
 		t.Fail()
@@ -383,10 +405,10 @@ func TestQueue(t *testing.T) {
 }
 
 func TestQueue2(t *testing.T) {
-	q := make(queue32, 0)
+	q := make(queue[uint32, interface{}], 0)
 	tests := []uint32{20, 30, 40}
 	for _, val := range tests {
-		q.Push(&node32{&Radix32{Value: val}, -1})
+		q.Push(&node[uint32, interface{}]{&Radix32{Value: val}, -1})
 	}
 	for _, val := range tests {
 		x := q.Pop()
@@ -395,18 +417,18 @@ func TestQueue2(t *testing.T) {
 			t.Fail()
 			continue
 		}
-		if x.Radix32.Value != val {
-			t.Logf("Expected %d, got %d\n", val, x.Radix32.Value)
+		if x.Radix.Value != val {
+			t.Logf("Expected %d, got %d\n", val, x.Radix.Value)
 			t.Fail()
 		}
 	}
 	if x := q.Pop(); x != nil {
-		t.Logf("Expected nil, got %d\n", x.Radix32.Value)
+		t.Logf("Expected nil, got %d\n", x.Radix.Value)
 		t.Fail()
 	}
 	// Push and pop again, see if that works too
 	for _, val := range tests {
-		q.Push(&node32{&Radix32{Value: val}, -1})
+		q.Push(&node[uint32, interface{}]{&Radix32{Value: val}, -1})
 	}
 	for _, val := range tests {
 		x := q.Pop()
@@ -415,8 +437,8 @@ func TestQueue2(t *testing.T) {
 			t.Fail()
 			continue
 		}
-		if x.Radix32.Value != val {
-			t.Logf("Expected %d, got %d\n", val, x.Radix32.Value)
+		if x.Radix.Value != val {
+			t.Logf("Expected %d, got %d\n", val, x.Radix.Value)
 			t.Fail()
 		}
 	}
@@ -427,6 +449,7 @@ func TestPanic32(t *testing.T) {
 	var k uint32
 	for k = 0; k <= 255; k++ {
 		r.Insert(k, 32, k)
+		validate(t, r)
 	}
 }
 
@@ -435,5 +458,193 @@ func TestPanic64(t *testing.T) {
 	var k uint64
 	for k = 0; k <= 255; k++ {
 		r.Insert(k, 64, k)
+		validate(t, r)
+	}
+}
+
+func TestInsert16(t *testing.T) {
+	r := New[uint16, interface{}]()
+	tests := map[uint16]int{0x8000: 1, 0x8100: 2, 0x4000: 3}
+	for k, v := range tests {
+		r.Insert(k, 8, v)
+		validate(t, r)
+	}
+	r.Do(func(r1 *Radix[uint16, interface{}], i int) {
+		t.Logf("(%2d): %016b/%d -> %d\n", i, r1.key, r1.bits, r1.Value)
+	})
+	for k, v := range tests {
+		if x := r.Find(k, 8); x == nil || x.Value != v {
+			t.Logf("Expected %d, got %v for %016b\n", v, x, k)
+			t.Fail()
+		}
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	r := New32Of[uint32]()
+	addRoute := func(s string, asn uint32) {
+		_, ipnet, _ := net.ParseCIDR(s)
+		n, mask := ipToUint(t, ipnet)
+		r.Insert(n, mask, asn)
+		validate(t, r)
+	}
+	addRoute("10.0.0.0/8", 10)
+	addRoute("10.20.0.0/14", 20)
+	addRoute("192.168.0.0/16", 192)
+
+	var sum uint32
+	r.Do(func(r1 *Radix32Of[uint32], i int) { sum += r1.Value })
+	if sum != 10+20+192 {
+		t.Logf("Expected %d, got %d\n", 10+20+192, sum)
+		t.Fail()
+	}
+	if x := r.Find(0x0A140000, 14); x == nil || x.Value != 20 {
+		t.Logf("Expected %d, got %v\n", 20, x)
+		t.Fail()
+	}
+	// Overwriting an existing key must not allocate, the value is not boxed.
+	if a := testing.AllocsPerRun(100, func() { r.Insert(0x0A140000, 14, 0xDEADBEEF) }); a != 0 {
+		t.Logf("Expected 0 allocations, got %f\n", a)
+		t.Fail()
+	}
+}
+
+func TestFindContract(t *testing.T) {
+	r := New32()
+	addRoute(t, r, "10.0.0.0/8", 10)
+	addRoute(t, r, "10.20.0.0/16", 20)
+	addRoute(t, r, "10.20.30.0/24", 30)
+
+	type result struct {
+		value interface{}
+		bits  int
+	}
+	tests := map[string]result{
+		"10.20.30.0/24": {uint32(30), 24}, // exact
+		"10.20.0.0/16":  {uint32(20), 16}, // exact, while a more specific exists
+		"10.20.30.0/23": {uint32(20), 16}, // covering, never the more specific /24
+		"10.20.31.0/24": {uint32(20), 16},
+		"10.0.0.0/7":    {nil, 0}, // nothing covers it
+		"11.0.0.0/8":    {nil, 0},
+	}
+	for s, res := range tests {
+		_, ipnet, _ := net.ParseCIDR(s)
+		n, bits := ipToUint(t, ipnet)
+		x := r.Find(n, bits)
+		switch {
+		case x == nil && res.value != nil:
+			t.Logf("Expected %d, got nil for %s\n", res.value, s)
+			t.Fail()
+		case x != nil && (x.Value != res.value || x.Bits() != res.bits):
+			t.Logf("Expected %d/%d, got %d/%d for %s\n", res.value, res.bits, x.Value, x.Bits(), s)
+			t.Fail()
+		}
+		// Get only returns exact matches.
+		g := r.Get(n, bits)
+		if exact := x != nil && x.Bits() == bits; exact != (g != nil) {
+			t.Logf("Expected Get to return %v for %s, got %v\n", exact, s, g)
+			t.Fail()
+		}
+	}
+
+	hosts := map[string]result{
+		"10.20.30.40/32": {uint32(30), 24},
+		"10.20.1.1/32":   {uint32(20), 16},
+		"10.1.1.1/32":    {uint32(10), 8},
+		"11.1.1.1/32":    {nil, 0},
+	}
+	for s, res := range hosts {
+		_, ipnet, _ := net.ParseCIDR(s)
+		n, _ := ipToUint(t, ipnet)
+		x, bits := r.LongestMatch(n)
+		if bits != res.bits || (x == nil) != (res.value == nil) || (x != nil && x.Value != res.value) {
+			t.Logf("Expected %d/%d, got %v/%d for %s\n", res.value, res.bits, x, bits, s)
+			t.Fail()
+		}
+	}
+}
+
+func TestTryInsert(t *testing.T) {
+	r := New32()
+	tests := map[bittest]error{
+		bittest{0x0A000000, 8}:  nil,
+		bittest{0x0A000000, 0}:  ErrInvalidPrefixLen,
+		bittest{0x0A000000, 33}: ErrInvalidPrefixLen,
+		bittest{0x0A000001, 8}:  ErrHostBitsSet,
+		bittest{0x0A000001, 32}: nil,
+	}
+	for test, expected := range tests {
+		if _, err := r.TryInsert(test.key, test.bit, 1); err != expected {
+			t.Logf("Expected %v for %032b/%d, got %v\n", expected, test.key, test.bit, err)
+			t.Fail()
+		}
+		if _, err := r.TryRemove(test.key, test.bit); err != expected {
+			t.Logf("Expected %v for removal of %032b/%d, got %v\n", expected, test.key, test.bit, err)
+			t.Fail()
+		}
+		validate(t, r)
+	}
+	x := New32().Insert(0x0A000000, 8, 1)
+	if _, err := x.TryInsert(0x0A000000, 8, 1); err != ErrNotRoot {
+		t.Logf("Expected %v, got %v\n", ErrNotRoot, err)
+		t.Fail()
+	}
+	func() {
+		defer func() {
+			if e := recover(); e != ErrHostBitsSet {
+				t.Logf("Expected panic with %v, got %v\n", ErrHostBitsSet, e)
+				t.Fail()
+			}
+		}()
+		r.Insert(0x0A000001, 8, 1)
+	}()
+	validate(t, r)
+}
+
+func TestUpdate(t *testing.T) {
+	r := New32Of[int]()
+	incr := func(old int, exists bool) (int, bool) { return old + 1, true }
+	decr := func(old int, exists bool) (int, bool) { return old - 1, old > 1 }
+	for _, k := range []uint32{0x0A000000, 0x0A140000, 0x0A000000, 0x0A140000, 0x0A000000} {
+		r.Update(k, 14, incr)
+		validate(t, r)
+	}
+	if x := r.Get(0x0A000000, 14); x == nil || x.Value != 3 {
+		t.Logf("Expected %d, got %v\n", 3, x)
+		t.Fail()
+	}
+	// A node without a key covering exactly n/bits gets the key.
+	r.Insert(0x0A200000, 14, 1)
+	if x := r.Update(0x0A000000, 10, incr); x == nil || x.Value != 1 || x.Bits() != 10 {
+		t.Logf("Expected %d, got %v\n", 1, x)
+		t.Fail()
+	}
+	validate(t, r)
+
+	for i, expected := range []int{1, 0} {
+		x := r.Update(0x0A140000, 14, decr)
+		if (expected == 0) != (x == nil) || (x != nil && x.Value != expected) {
+			t.Logf("Expected %d after %d decrements, got %v\n", expected, i+1, x)
+			t.Fail()
+		}
+		validate(t, r)
+	}
+	if x := r.Get(0x0A140000, 14); x != nil {
+		t.Logf("Expected 10.20.0.0/14 to be removed, got %v\n", x)
+		t.Fail()
+	}
+	called := false
+	if x := r.Update(0xC0A80000, 16, func(old int, exists bool) (int, bool) { called = !exists; return 0, false }); x != nil || !called {
+		t.Logf("Expected nothing to be inserted, got %v\n", x)
+		t.Fail()
+	}
+	if r.Get(0xC0A80000, 16) != nil {
+		t.Logf("Expected 192.168.0.0/16 not to be inserted\n")
+		t.Fail()
+	}
+	validate(t, r)
+	if _, err := r.TryUpdate(0x0A000001, 8, incr); err != ErrHostBitsSet {
+		t.Logf("Expected %v, got %v\n", ErrHostBitsSet, err)
+		t.Fail()
 	}
 }
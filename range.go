@@ -0,0 +1,170 @@
+package bitradix
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"math/bits"
+)
+
+// ErrInvalidRange is returned by TryInsertRange when the first address of a
+// range is larger than the last.
+var ErrInvalidRange = errors.New("bitradix: invalid range")
+
+// Range is a range of addresses, from Lo up to and including Hi.
+type Range[K Key] struct {
+	Lo K
+	Hi K
+}
+
+// InsertRange inserts the value v for the addresses lo up to and including hi,
+// as the minimal set of prefixes that covers exactly that range, possibly
+// silently overwriting existing values. As a prefix of 0 bits can not be
+// stored, the whole address space takes two prefixes. r must be the root of the
+// tree. InsertRange panics when TryInsertRange would return an error.
+func (r *Radix[K, V]) InsertRange(lo, hi K, v V) {
+	if err := r.TryInsertRange(lo, hi, v); err != nil {
+		panic(err)
+	}
+}
+
+// TryInsertRange is like InsertRange, but returns an error instead of panicking.
+// It returns ErrNotRoot when r is not the root of the tree and ErrInvalidRange
+// when lo is larger than hi, the tree is not changed then.
+func (r *Radix[K, V]) TryInsertRange(lo, hi K, v V) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	if compareKey(lo, hi) > 0 {
+		return ErrInvalidRange
+	}
+	for {
+		// The longest prefix starting at lo that does not extend beyond hi.
+		b := max(bitSize[K]()-trailingZeros(lo), 1)
+		for compareKey(lastKey(lo, b), hi) > 0 {
+			b++
+		}
+		r.insert(lo, b, v)
+		last := lastKey(lo, b)
+		if last == hi {
+			return nil
+		}
+		lo = nextKey(last)
+	}
+}
+
+// Ranges returns an iterator over the ranges of addresses that get the same
+// value from a lookup in r, in increasing order. Adjacent prefixes with equal
+// values, and the parts of a prefix that are not covered by longer prefixes,
+// are merged into one range. Addresses that no prefix contains are not in any
+// range. Ranges panics with ErrNotRoot when r is not the root of a tree.
+func Ranges[K Key, V comparable](r *Radix[K, V]) iter.Seq2[Range[K], V] {
+	if r.parent != nil {
+		panic(ErrNotRoot)
+	}
+	return func(yield func(Range[K], V) bool) {
+		type open struct {
+			last  K
+			value V
+		}
+		var (
+			stack []open // the prefixes containing the current address, longest last
+			pos   K      // the first address not yet in a range
+			end   bool   // all addresses are in a range
+			cur   Range[K]
+			value V
+			ok    bool // cur holds a range not yet yielded
+		)
+		// add adds pos up to and including last with value v, it returns false when yield does.
+		add := func(last K, v V) bool {
+			if end || compareKey(pos, last) > 0 {
+				return true
+			}
+			if ok && v == value && nextKey(cur.Hi) == pos {
+				cur.Hi = last
+			} else {
+				if ok && !yield(cur, value) {
+					return false
+				}
+				cur, value, ok = Range[K]{pos, last}, v, true
+			}
+			pos = nextKey(last)
+			end = last == lastKey(last, 0)
+			return true
+		}
+		// pop closes the prefixes that end before n, or all of them when all is true.
+		pop := func(n K, all bool) bool {
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if !all && compareKey(top.last, n) >= 0 {
+					return true
+				}
+				if !add(top.last, top.value) {
+					return false
+				}
+				stack = stack[:len(stack)-1]
+			}
+			return true
+		}
+		for p, v := range r.All() {
+			if !pop(p.Key, false) {
+				return
+			}
+			if len(stack) > 0 && compareKey(pos, p.Key) < 0 {
+				if !add(prevKey(p.Key), stack[len(stack)-1].value) {
+					return
+				}
+			}
+			pos, end = p.Key, false
+			stack = append(stack, open{lastKey(p.Key, p.Bits), v})
+		}
+		if pop(pos, true) && ok {
+			yield(cur, value)
+		}
+	}
+}
+
+// compareKey compares a and b as unsigned numbers.
+func compareKey[K Key](a, b K) int {
+	ahi, alo := words(a)
+	bhi, blo := words(b)
+	if c := cmp.Compare(ahi, bhi); c != 0 {
+		return c
+	}
+	return cmp.Compare(alo, blo)
+}
+
+// lastKey returns n with all bits beyond the first bits bits set, the last
+// address in the prefix n/bits.
+func lastKey[K Key](n K, bits int) K {
+	hi, lo := words(n)
+	mhi, mlo := mask128(128 - bitSize[K]() + bits)
+	return fromWords[K](hi|^mhi, lo|^mlo)
+}
+
+// nextKey returns n+1, it wraps around to 0.
+func nextKey[K Key](n K) K {
+	hi, lo := words(n)
+	lo, c := bits.Add64(lo, 1, 0)
+	return fromWords[K](hi+c, lo)
+}
+
+// prevKey returns n-1, it wraps around to the largest key.
+func prevKey[K Key](n K) K {
+	hi, lo := words(n)
+	lo, b := bits.Sub64(lo, 1, 0)
+	return fromWords[K](hi-b, lo)
+}
+
+// trailingZeros returns the number of trailing zero bits in n, the number of
+// bits in K when n is 0.
+func trailingZeros[K Key](n K) int {
+	hi, lo := words(n)
+	if lo != 0 {
+		return bits.TrailingZeros64(lo)
+	}
+	if hi != 0 {
+		return 64 + bits.TrailingZeros64(hi)
+	}
+	return bitSize[K]()
+}
@@ -0,0 +1,131 @@
+package bitradix
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestInsertRange(t *testing.T) {
+	tests := []struct {
+		lo, hi uint32
+		want   []string
+	}{
+		{0x0A000001, 0x0A000006, []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{0x0A000000, 0x0A0000FF, []string{"10.0.0.0/24"}},
+		{0x0A0000FF, 0x0A0000FF, []string{"10.0.0.255/32"}},
+		{0xC0A80000, 0xC0AB0100, []string{"192.168.0.0/15", "192.170.0.0/16", "192.171.0.0/24", "192.171.1.0/32"}},
+		{0x00000000, 0xFFFFFFFF, []string{"0.0.0.0/1", "128.0.0.0/1"}},
+		{0xFFFFFFFE, 0xFFFFFFFF, []string{"255.255.255.254/31"}},
+	}
+	for _, tt := range tests {
+		r := New32()
+		r.InsertRange(tt.lo, tt.hi, 1)
+		validate(t, r)
+		var got []string
+		for p := range r.All() {
+			got = append(got, formatPrefix(p.Key, p.Bits))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Logf("Expected %v for %08x-%08x, got %v\n", tt.want, tt.lo, tt.hi, got)
+			t.Fail()
+		}
+	}
+	if err := New32().TryInsertRange(2, 1, 1); err != ErrInvalidRange {
+		t.Logf("Expected %v, got %v\n", ErrInvalidRange, err)
+		t.Fail()
+	}
+}
+
+func TestRanges(t *testing.T) {
+	r := New32Of[string]()
+	r.InsertRange(0x0A000001, 0x0A000006, "a")
+	r.InsertRange(0x0A000007, 0x0A000010, "a") // adjacent, same value
+	r.Insert(0x0A000100, 24, "b")
+	r.Insert(0x0A000180, 25, "c") // splits 10.0.1.0/24
+	r.Insert(0x0A000200, 24, "b") // adjacent to 10.0.1.128/25, different value
+	r.InsertRange(0xFFFFFFF0, 0xFFFFFFFF, "d")
+	want := []string{
+		"10.0.0.1-10.0.0.16 a",
+		"10.0.1.0-10.0.1.127 b",
+		"10.0.1.128-10.0.1.255 c",
+		"10.0.2.0-10.0.2.255 b",
+		"255.255.255.240-255.255.255.255 d",
+	}
+	var got []string
+	for rg, v := range Ranges(r) {
+		got = append(got, fmt.Sprintf("%s-%s %s", formatAddr(rg.Lo), formatAddr(rg.Hi), v))
+	}
+	if !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
+
+// formatAddr formats the IPv4 address n.
+func formatAddr(n uint32) string {
+	s := formatPrefix(n, 32)
+	return s[:len(s)-3]
+}
+
+func TestRangesRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		r := randomSetTree(t, rnd, 30, 15, 0, 3)
+		// The ranges must be increasing, not adjacent with the same value and give
+		// the same value as a lookup at and around their ends.
+		var prev Range[uint32]
+		prevValue, first := 0, true
+		for rg, v := range Ranges(r) {
+			if !first && (rg.Lo <= prev.Hi || (rg.Lo == prev.Hi+1 && v == prevValue)) {
+				t.Logf("Range %08x-%08x %d follows %08x-%08x %d\n", rg.Lo, rg.Hi, v, prev.Lo, prev.Hi, prevValue)
+				t.FailNow()
+			}
+			for _, n := range []uint32{rg.Lo, rg.Hi, rg.Lo + (rg.Hi-rg.Lo)/2} {
+				if w, ok := lookup(r, n); !ok || w != v {
+					t.Logf("Expected %d for %08x in %08x-%08x, got %d (%v)\n%s", v, n, rg.Lo, rg.Hi, w, ok, r)
+					t.FailNow()
+				}
+			}
+			for _, n := range []uint32{rg.Lo - 1, rg.Hi + 1} {
+				if w, ok := lookup(r, n); ok && w == v && n != 0 && n != 0xFFFFFFFF {
+					t.Logf("%08x next to %08x-%08x has the same value %d\n%s", n, rg.Lo, rg.Hi, v, r)
+					t.FailNow()
+				}
+			}
+			prev, prevValue, first = rg, v, false
+		}
+		// Inserting the ranges in a new tree gives the same ranges.
+		r2 := New32Of[int]()
+		for rg, v := range Ranges(r) {
+			r2.InsertRange(rg.Lo, rg.Hi, v)
+		}
+		validate(t, r2)
+		if !slices.Equal(rangeList(r), rangeList(r2)) {
+			t.Logf("Expected the same ranges\n%s\n%s", r, r2)
+			t.FailNow()
+		}
+	}
+}
+
+func rangeList[K Key](r *Radix[K, int]) []string {
+	var s []string
+	for rg, v := range Ranges(r) {
+		s = append(s, fmt.Sprint(rg, v))
+	}
+	return s
+}
+
+func TestRanges64(t *testing.T) {
+	r := New64Of[int]()
+	r.InsertRange(0x2001_0db8_0000_0000, 0x2001_0db8_ffff_ffff, 1)
+	r.InsertRange(0x2001_0db9_0000_0000, 0x2001_0db9_0000_0fff, 1)
+	r.InsertRange(0xffff_ffff_ffff_fff0, 0xffff_ffff_ffff_ffff, 2)
+	validate(t, r)
+	want := []string{"{2306139568115548160 2306139572410519551} 1", "{18446744073709551600 18446744073709551615} 2"}
+	if got := rangeList(r); !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
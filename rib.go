@@ -0,0 +1,115 @@
+package bitradix
+
+import "slices"
+
+// RIB is a routing information base for IPv4 prefixes: every prefix holds a set
+// of paths, at most one per source (e.g. a BGP peer), ordered from best to worst
+// by a user supplied comparator. The prefixes are stored in a Radix32Of, the
+// paths of the default route 0.0.0.0/0 are kept apart.
+type RIB[S comparable, A any] struct {
+	tree    *Radix32Of[[]Path[S, A]]
+	def     []Path[S, A]
+	compare func(a, b A) int
+}
+
+// Path is a path to a prefix in a RIB, Source identifies where it was learned
+// from and Attrs holds its attributes.
+type Path[S comparable, A any] struct {
+	Source S
+	Attrs  A
+}
+
+// NewRIB returns an empty RIB. compare returns a negative number when path
+// attributes a are better than b, a positive number when they are worse and 0
+// when they are equally good, in which case the path added first is preferred.
+func NewRIB[S comparable, A any](compare func(a, b A) int) *RIB[S, A] {
+	return &RIB[S, A]{tree: New32Of[[]Path[S, A]](), compare: compare}
+}
+
+// AddPath adds the path with attributes a from source src to prefix n/bits,
+// replacing the path from src when there is one. It returns the same errors as
+// Radix.TryInsert, except that 0.0.0.0/0 is accepted.
+func (t *RIB[S, A]) AddPath(n uint32, bits int, src S, a A) error {
+	add := func(paths []Path[S, A], _ bool) ([]Path[S, A], bool) {
+		paths = slices.DeleteFunc(paths, func(p Path[S, A]) bool { return p.Source == src })
+		// Insert before the first worse path.
+		i := slices.IndexFunc(paths, func(p Path[S, A]) bool { return t.compare(a, p.Attrs) < 0 })
+		if i < 0 {
+			i = len(paths)
+		}
+		return slices.Insert(paths, i, Path[S, A]{src, a}), true
+	}
+	if bits == 0 {
+		if n != 0 {
+			return ErrHostBitsSet
+		}
+		t.def, _ = add(t.def, t.def != nil)
+		return nil
+	}
+	_, err := t.tree.TryUpdate(n, bits, add)
+	return err
+}
+
+// WithdrawPath removes the path from source src from prefix n/bits. The prefix
+// is removed when it has no paths left. It returns the attributes of the path
+// removed and true, or false when there was no such path. A prefix that AddPath
+// would reject is never found.
+func (t *RIB[S, A]) WithdrawPath(n uint32, bits int, src S) (a A, ok bool) {
+	withdraw := func(paths []Path[S, A], _ bool) ([]Path[S, A], bool) {
+		if i := slices.IndexFunc(paths, func(p Path[S, A]) bool { return p.Source == src }); i >= 0 {
+			a, ok = paths[i].Attrs, true
+			paths = slices.Delete(paths, i, i+1)
+		}
+		return paths, len(paths) > 0
+	}
+	if bits == 0 && n == 0 {
+		if paths, keep := withdraw(t.def, t.def != nil); keep {
+			t.def = paths
+		} else {
+			t.def = nil
+		}
+		return a, ok
+	}
+	t.tree.TryUpdate(n, bits, withdraw)
+	return a, ok
+}
+
+// Paths returns the paths of exactly prefix n/bits, best first, or nil when there
+// are none. Paths panics like Radix.Get, except for a prefix length of 0.
+func (t *RIB[S, A]) Paths(n uint32, bits int) []Path[S, A] {
+	return slices.Clone(t.paths(n, bits))
+}
+
+// Best returns the best path of exactly prefix n/bits, or false when there are
+// no paths. Best panics like Paths.
+func (t *RIB[S, A]) Best(n uint32, bits int) (p Path[S, A], ok bool) {
+	if paths := t.paths(n, bits); paths != nil {
+		return paths[0], true
+	}
+	return p, false
+}
+
+// Lookup returns the longest prefix that contains the host address n and its
+// best path, or false when no prefix contains n.
+func (t *RIB[S, A]) Lookup(n uint32) (Prefix[uint32], Path[S, A], bool) {
+	if x, bits := t.tree.LongestMatch(n); x != nil {
+		return Prefix[uint32]{x.key, bits}, x.Value[0], true
+	}
+	if t.def != nil {
+		return Prefix[uint32]{}, t.def[0], true
+	}
+	return Prefix[uint32]{}, Path[S, A]{}, false
+}
+
+func (t *RIB[S, A]) paths(n uint32, bits int) []Path[S, A] {
+	if bits == 0 {
+		if n != 0 {
+			return nil
+		}
+		return t.def
+	}
+	if x := t.tree.Get(n, bits); x != nil {
+		return x.Value
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package bitradix
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+// ribAttrs are the path attributes used in the tests, a lower preference is better.
+type ribAttrs struct {
+	pref    int
+	nexthop string
+}
+
+func newTestRIB() *RIB[string, ribAttrs] {
+	return NewRIB[string](func(a, b ribAttrs) int { return cmp.Compare(a.pref, b.pref) })
+}
+
+func ribSources(paths []Path[string, ribAttrs]) []string {
+	var s []string
+	for _, p := range paths {
+		s = append(s, p.Source)
+	}
+	return s
+}
+
+func TestRIB(t *testing.T) {
+	rib := newTestRIB()
+	adds := []struct {
+		n    uint32
+		bits int
+		src  string
+		pref int
+	}{
+		{0x0A000000, 8, "peer1", 20},
+		{0x0A000000, 8, "peer2", 10},
+		{0x0A000000, 8, "peer3", 20},
+		{0x0A000000, 8, "peer4", 30},
+		{0x0A140000, 16, "peer1", 10},
+		{0x00000000, 0, "peer2", 10},
+	}
+	for _, a := range adds {
+		if err := rib.AddPath(a.n, a.bits, a.src, ribAttrs{a.pref, a.src}); err != nil {
+			t.Logf("AddPath of %s from %s: %v\n", formatPrefix(a.n, a.bits), a.src, err)
+			t.Fail()
+		}
+	}
+	validate(t, rib.tree)
+	// Equally good paths keep the order in which they were added.
+	if s := ribSources(rib.Paths(0x0A000000, 8)); !slices.Equal(s, []string{"peer2", "peer1", "peer3", "peer4"}) {
+		t.Logf("Expected peer2, peer1, peer3, peer4, got %v\n", s)
+		t.Fail()
+	}
+	// Replacing a path moves it.
+	rib.AddPath(0x0A000000, 8, "peer4", ribAttrs{5, "peer4"})
+	rib.AddPath(0x0A000000, 8, "peer1", ribAttrs{25, "peer1"})
+	if s := ribSources(rib.Paths(0x0A000000, 8)); !slices.Equal(s, []string{"peer4", "peer2", "peer3", "peer1"}) {
+		t.Logf("Expected peer4, peer2, peer3, peer1, got %v\n", s)
+		t.Fail()
+	}
+
+	lookups := map[uint32]struct {
+		p   Prefix[uint32]
+		src string
+	}{
+		0x0A140101: {Prefix[uint32]{0x0A140000, 16}, "peer1"},
+		0x0A150101: {Prefix[uint32]{0x0A000000, 8}, "peer4"},
+		0xC0A80101: {Prefix[uint32]{0, 0}, "peer2"},
+	}
+	for n, l := range lookups {
+		if p, path, ok := rib.Lookup(n); !ok || p != l.p || path.Source != l.src {
+			t.Logf("Expected %v from %s for %08x, got %v from %s (%v)\n", l.p, l.src, n, p, path.Source, ok)
+			t.Fail()
+		}
+	}
+
+	if a, ok := rib.WithdrawPath(0x0A000000, 8, "peer4"); !ok || a.pref != 5 {
+		t.Logf("Expected to withdraw the path from peer4 with preference 5, got %v (%v)\n", a, ok)
+		t.Fail()
+	}
+	if _, ok := rib.WithdrawPath(0x0A000000, 8, "peer4"); ok {
+		t.Logf("Expected the path from peer4 to be gone\n")
+		t.Fail()
+	}
+	if p, ok := rib.Best(0x0A000000, 8); !ok || p.Source != "peer2" {
+		t.Logf("Expected peer2 as best path, got %v (%v)\n", p, ok)
+		t.Fail()
+	}
+	// Withdrawing the last path removes the prefix.
+	rib.WithdrawPath(0x0A140000, 16, "peer1")
+	if x := rib.tree.Get(0x0A140000, 16); x != nil {
+		t.Logf("Expected 10.20.0.0/16 to be removed, got %v\n", x)
+		t.Fail()
+	}
+	validate(t, rib.tree)
+	if p, path, ok := rib.Lookup(0x0A140101); !ok || p != (Prefix[uint32]{0x0A000000, 8}) || path.Source != "peer2" {
+		t.Logf("Expected 10.0.0.0/8 from peer2, got %v from %s (%v)\n", p, path.Source, ok)
+		t.Fail()
+	}
+	if p, ok := rib.Best(0x0A000000, 0); ok {
+		t.Logf("Expected no path for 10.0.0.0/0, got %v\n", p)
+		t.Fail()
+	}
+	rib.WithdrawPath(0, 0, "peer2")
+	if _, _, ok := rib.Lookup(0xC0A80101); ok {
+		t.Logf("Expected no route for 192.168.1.1\n")
+		t.Fail()
+	}
+
+	if err := rib.AddPath(0x0A000001, 8, "peer1", ribAttrs{}); err != ErrHostBitsSet {
+		t.Logf("Expected %v, got %v\n", ErrHostBitsSet, err)
+		t.Fail()
+	}
+	if _, ok := rib.WithdrawPath(0x0A000000, 33, "peer1"); ok {
+		t.Logf("Expected an invalid prefix not to be found\n")
+		t.Fail()
+	}
+}
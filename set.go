@@ -0,0 +1,228 @@
+package bitradix
+
+// The set operations walk both trees together, prefix by prefix, jumping over
+// the bits where nothing happens. During the walk a and b are the topmost nodes
+// of the trees in the prefix being visited, or nil when the tree has nothing
+// there. The result is collected in the order of All and built with BuildFromSorted.
+
+// Union returns a new tree holding the prefixes of a and b. When a prefix is in
+// both trees its value is merge(a's value, b's value). Union panics with
+// ErrNotRoot when a or b is not the root of a tree.
+func Union[K Key, V any](a, b *Radix[K, V], merge func(x, y V) V) *Radix[K, V] {
+	if a.parent != nil || b.parent != nil {
+		panic(ErrNotRoot)
+	}
+	s := &sorted[K, V]{}
+	var n K
+	s.union(n, 0, a, b, merge)
+	return s.build()
+}
+
+// Intersect returns a new tree covering the addresses that are covered by both
+// a and b. The result holds the prefixes of a that lie within a prefix of b and
+// the prefixes of b that lie within a prefix of a. Every prefix has the value of
+// the longest prefix of a that contains it, so that a lookup of an address in the
+// result finds a's value, or nothing when b does not cover the address.
+// Intersect panics with ErrNotRoot when a or b is not the root of a tree.
+func Intersect[K Key, V, W any](a *Radix[K, V], b *Radix[K, W]) *Radix[K, V] {
+	if a.parent != nil || b.parent != nil {
+		panic(ErrNotRoot)
+	}
+	s := &sorted[K, V]{}
+	var (
+		n K
+		v V
+	)
+	intersect(s, n, 0, a, b, v, false, false)
+	return s.build()
+}
+
+// Difference returns a new tree covering the addresses that are covered by a and
+// not by b. Prefixes of a that partly overlap a prefix of b are split into the
+// largest prefixes outside of b. Every prefix has the value of the longest
+// prefix of a that contains it, so that a lookup of an address in the result
+// finds a's value, or nothing when b covers the address. Difference panics with
+// ErrNotRoot when a or b is not the root of a tree.
+func Difference[K Key, V, W any](a *Radix[K, V], b *Radix[K, W]) *Radix[K, V] {
+	if a.parent != nil || b.parent != nil {
+		panic(ErrNotRoot)
+	}
+	s := &sorted[K, V]{}
+	var (
+		n K
+		v V
+	)
+	difference(s, n, 0, a, b, v, false)
+	return s.build()
+}
+
+func (s *sorted[K, V]) union(n K, bits int, a, b *Radix[K, V], merge func(x, y V) V) {
+	if a == nil || b == nil {
+		s.addTree(a)
+		s.addTree(b)
+		return
+	}
+	if d := next(a, b); d > bits {
+		s.union(maskKey(a.key, d), d, a, b, merge)
+		return
+	}
+	switch ak, bk := a.skip == bits && a.bits != 0, b.skip == bits && b.bits != 0; {
+	case ak && bk:
+		s.add(n, bits, merge(a.Value, b.Value))
+	case ak:
+		s.add(n, bits, a.Value)
+	case bk:
+		s.add(n, bits, b.Value)
+	}
+	for c := 0; c < 2; c++ {
+		s.union(withBit(n, bits, c), bits+1, below(a, bits, c), below(b, bits, c), merge)
+	}
+}
+
+// intersect visits n/bits, v is the value of the longest prefix of a containing
+// n/bits when ina is true, inb is true when a prefix of b contains n/bits.
+func intersect[K Key, V, W any](s *sorted[K, V], n K, bits int, a *Radix[K, V], b *Radix[K, W], v V, ina, inb bool) {
+	if a != nil && a.skip == bits && a.bits != 0 {
+		v, ina = a.Value, true
+	}
+	if b != nil && b.skip == bits && b.bits != 0 {
+		inb = true
+	}
+	switch {
+	case ina && inb:
+		// Everything in a from here on is in the result.
+		s.add(n, bits, v)
+		if a != nil && a.skip == bits {
+			s.addTree(a.branch[0])
+			s.addTree(a.branch[1])
+			return
+		}
+		s.addTree(a)
+		return
+	case inb:
+		s.addTree(a)
+		return
+	case ina && b == nil, !ina && a == nil:
+		return
+	}
+	if d := next(a, b); d > bits {
+		intersect(s, maskKey(keyOf(a, b), d), d, a, b, v, ina, inb)
+		return
+	}
+	for c := 0; c < 2; c++ {
+		intersect(s, withBit(n, bits, c), bits+1, below(a, bits, c), below(b, bits, c), v, ina, inb)
+	}
+}
+
+// difference visits n/bits, v is the value of the longest prefix of a containing
+// n/bits when ina is true.
+func difference[K Key, V, W any](s *sorted[K, V], n K, bits int, a *Radix[K, V], b *Radix[K, W], v V, ina bool) {
+	if a != nil && a.skip == bits && a.bits != 0 {
+		v, ina = a.Value, true
+	}
+	if b != nil && b.skip == bits && b.bits != 0 {
+		return
+	}
+	if b == nil {
+		if ina {
+			s.add(n, bits, v)
+		}
+		if a != nil && a.skip == bits {
+			s.addTree(a.branch[0])
+			s.addTree(a.branch[1])
+			return
+		}
+		s.addTree(a)
+		return
+	}
+	if !ina {
+		if a == nil {
+			return
+		}
+		// Nothing to split until a prefix of a is found.
+		if d := next(a, b); d > bits {
+			difference(s, maskKey(a.key, d), d, a, b, v, ina)
+			return
+		}
+	}
+	for c := 0; c < 2; c++ {
+		difference(s, withBit(n, bits, c), bits+1, below(a, bits, c), below(b, bits, c), v, ina)
+	}
+}
+
+// next returns the length of the longest prefix the topmost nodes a and b of
+// the prefix being visited both lie in, nil nodes are ignored.
+func next[K Key, V, W any](a *Radix[K, V], b *Radix[K, W]) int {
+	switch {
+	case a == nil:
+		return b.skip
+	case b == nil:
+		return a.skip
+	}
+	return common(a.key, b.key, min(a.skip, b.skip))
+}
+
+// keyOf returns the key of a, or of b when a is nil.
+func keyOf[K Key, V, W any](a *Radix[K, V], b *Radix[K, W]) K {
+	if a != nil {
+		return a.key
+	}
+	return b.key
+}
+
+// below returns the topmost node in branch c of the prefix of bits bits that x,
+// the topmost node in that prefix, is in.
+func below[K Key, V any](x *Radix[K, V], bits, c int) *Radix[K, V] {
+	switch {
+	case x == nil:
+		return nil
+	case x.skip == bits:
+		return x.branch[c]
+	case int(bitK(x.key, bitSize[K]()-1-bits)) == c:
+		return x
+	}
+	return nil
+}
+
+// withBit returns n with the bit after the first bits bits set to c.
+func withBit[K Key](n K, bits, c int) K {
+	if c == 0 {
+		return n
+	}
+	hi, lo := words(n)
+	if k := bitSize[K]() - 1 - bits; k >= 64 {
+		hi |= 1 << uint(k-64)
+	} else {
+		lo |= 1 << uint(k)
+	}
+	return fromWords[K](hi, lo)
+}
+
+// sorted collects prefixes in the order of All for BuildFromSorted.
+type sorted[K Key, V any] struct {
+	keys   []K
+	bits   []int
+	values []V
+}
+
+func (s *sorted[K, V]) add(n K, bits int, v V) {
+	s.keys = append(s.keys, n)
+	s.bits = append(s.bits, bits)
+	s.values = append(s.values, v)
+}
+
+// addTree adds the prefixes in the subtree x.
+func (s *sorted[K, V]) addTree(x *Radix[K, V]) {
+	x.walk(func(y *Radix[K, V]) bool {
+		s.add(y.key, y.bits, y.Value)
+		return true
+	}, false)
+}
+
+func (s *sorted[K, V]) build() *Radix[K, V] {
+	r, err := BuildFromSorted(s.keys, s.bits, s.values)
+	if err != nil {
+		panic(err) // the walks produce sorted, valid prefixes
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package bitradix
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// randomSetTree returns a tree with n prefixes of 4 to maxBits bits in
+// 0.0.0.0/4, so that the prefixes of different trees overlap, with values from
+// base up to base+values.
+func randomSetTree(t *testing.T, rnd *rand.Rand, n, maxBits, base, values int) *Radix32Of[int] {
+	r := New32Of[int]()
+	for i := 0; i < n; i++ {
+		bits := 4 + rnd.Intn(maxBits-3)
+		r.Insert(maskKey(rnd.Uint32()>>4, bits), bits, base+rnd.Intn(values))
+		validate(t, r)
+	}
+	return r
+}
+
+// prefixList returns the prefixes in r and their values, in the order of All.
+func prefixList[K Key, V any](r *Radix[K, V]) []string {
+	var s []string
+	for p, v := range r.All() {
+		s = append(s, fmt.Sprint(formatPrefix(p.Key, p.Bits), " ", v))
+	}
+	return s
+}
+
+// lookup returns the value of the longest prefix in r containing n.
+func lookup(r *Radix32Of[int], n uint32) (int, bool) {
+	if x, _ := r.LongestMatch(n); x != nil {
+		return x.Value, true
+	}
+	return 0, false
+}
+
+func TestUnion(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		a, b := randomSetTree(t, rnd, 40, 20, 0, 1000), randomSetTree(t, rnd, 40, 20, 1000, 1000)
+		u := Union(a, b, func(x, y int) int { return x + y })
+		validate(t, u)
+		want := map[Prefix[uint32]]int{}
+		for p, v := range a.All() {
+			want[p] = v
+		}
+		for p, v := range b.All() {
+			want[p] += v
+		}
+		count := 0
+		for p, v := range u.All() {
+			if w, ok := want[p]; !ok || v != w {
+				t.Logf("Expected %d (%v) for %s, got %d\n", w, ok, formatPrefix(p.Key, p.Bits), v)
+				t.Fail()
+			}
+			count++
+		}
+		if count != len(want) {
+			t.Logf("Expected %d prefixes, got %d\n", len(want), count)
+			t.Fail()
+		}
+	}
+}
+
+func TestIntersectDifference(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		a, b := randomSetTree(t, rnd, 40, 20, 0, 1000), randomSetTree(t, rnd, 20, 20, 1000, 1000)
+		in, diff := Intersect(a, b), Difference(a, b)
+		validate(t, in)
+		validate(t, diff)
+		for j := 0; j < 2000; j++ {
+			n := rnd.Uint32() >> 3 // half of the addresses is outside 0.0.0.0/4
+			av, ina := lookup(a, n)
+			_, inb := lookup(b, n)
+			if v, ok := lookup(in, n); ok != (ina && inb) || (ok && v != av) {
+				t.Logf("Intersect: expected %d (%v) for %08x, got %d (%v)\n%s\n%s\n%s", av, ina && inb, n, v, ok, a, b, in)
+				t.FailNow()
+			}
+			if v, ok := lookup(diff, n); ok != (ina && !inb) || (ok && v != av) {
+				t.Logf("Difference: expected %d (%v) for %08x, got %d (%v)\n%s\n%s\n%s", av, ina && !inb, n, v, ok, a, b, diff)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestDifference(t *testing.T) {
+	customers := New32Of[string]()
+	customers.Insert(0x0A000000, 8, "a")
+	customers.Insert(0x0A010000, 16, "b")
+	customers.Insert(0xC0A80000, 16, "c")
+	allocations := New32Of[struct{}]()
+	allocations.Insert(0x0A000000, 9, struct{}{})
+	allocations.Insert(0x0A800000, 10, struct{}{})
+	allocations.Insert(0x0AC00000, 11, struct{}{})
+	allocations.Insert(0xC0A80000, 15, struct{}{})
+	want := []string{"10.224.0.0/11 a"}
+	if got := prefixList(Difference(customers, allocations)); !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
+
+func TestIntersect64(t *testing.T) {
+	a := New64Of[int]()
+	a.Insert(0x2001_0db8_0000_0000, 32, 1)
+	a.Insert(0x2001_0db8_0001_0000, 48, 2)
+	b := New64Of[bool]()
+	b.Insert(0x2001_0db8_0000_0000, 47, true)
+	want := []string{"2001:db8::/47 1", "2001:db8:1::/48 2"}
+	if got := prefixList(Intersect(a, b)); !slices.Equal(got, want) {
+		t.Logf("Expected %v, got %v\n", want, got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,136 @@
+package bitradix
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Table is a routing table for IPv4 and IPv6 prefixes. IPv4 prefixes are
+// stored in a Radix32Of, IPv6 prefixes in a Radix128Of. IPv4-mapped IPv6
+// prefixes and addresses (::ffff:a.b.c.d) are handled as IPv4.
+type Table[V any] struct {
+	v4 *Radix32Of[V]
+	v6 *Radix128Of[V]
+	// The trees can not hold a zero length prefix, the default routes are kept here.
+	def4, def6 *V
+}
+
+// NewTable returns an empty, initialized Table.
+func NewTable[V any]() *Table[V] {
+	return &Table[V]{v4: New32Of[V](), v6: New128Of[V]()}
+}
+
+// InsertPrefix inserts the value v under prefix p, possibly silently overwriting
+// an existing value. The host bits of p are ignored. It returns
+// ErrInvalidPrefixLen when p is not valid, the table is not changed then.
+func (t *Table[V]) InsertPrefix(p netip.Prefix, v V) error {
+	p, ok := canonical(p)
+	if !ok {
+		return ErrInvalidPrefixLen
+	}
+	if p.Bits() == 0 {
+		*t.def(p) = &v
+		return nil
+	}
+	if p.Addr().Is4() {
+		t.v4.Insert(addrToUint32(p.Addr()), p.Bits(), v)
+		return nil
+	}
+	t.v6.Insert(addrToUint128(p.Addr()), p.Bits(), v)
+	return nil
+}
+
+// RemovePrefix removes prefix p from the table. It returns the value that
+// was stored and true, or false when p was not found.
+func (t *Table[V]) RemovePrefix(p netip.Prefix) (v V, ok bool) {
+	if _, ok := t.Get(p); !ok {
+		return v, false
+	}
+	p, _ = canonical(p)
+	if p.Bits() == 0 {
+		d := t.def(p)
+		v, *d = **d, nil
+		return v, true
+	}
+	if p.Addr().Is4() {
+		return t.v4.Remove(addrToUint32(p.Addr()), p.Bits()).Value, true
+	}
+	return t.v6.Remove(addrToUint128(p.Addr()), p.Bits()).Value, true
+}
+
+// Get returns the value stored under exactly prefix p.
+func (t *Table[V]) Get(p netip.Prefix) (v V, ok bool) {
+	p, ok = canonical(p)
+	if !ok {
+		return v, false
+	}
+	if p.Bits() == 0 {
+		if d := *t.def(p); d != nil {
+			return *d, true
+		}
+		return v, false
+	}
+	if p.Addr().Is4() {
+		if x := t.v4.Get(addrToUint32(p.Addr()), p.Bits()); x != nil {
+			return x.Value, true
+		}
+		return v, false
+	}
+	if x := t.v6.Get(addrToUint128(p.Addr()), p.Bits()); x != nil {
+		return x.Value, true
+	}
+	return v, false
+}
+
+// LookupAddr returns the value of the longest prefix that contains a.
+func (t *Table[V]) LookupAddr(a netip.Addr) (v V, ok bool) {
+	if !a.IsValid() {
+		return v, false
+	}
+	a = a.Unmap()
+	if a.Is4() {
+		if x, _ := t.v4.LongestMatch(addrToUint32(a)); x != nil {
+			return x.Value, true
+		}
+		if t.def4 != nil {
+			return *t.def4, true
+		}
+		return v, false
+	}
+	if x, _ := t.v6.LongestMatch(addrToUint128(a)); x != nil {
+		return x.Value, true
+	}
+	if t.def6 != nil {
+		return *t.def6, true
+	}
+	return v, false
+}
+
+// def returns the default route slot for the address family of p.
+func (t *Table[V]) def(p netip.Prefix) **V {
+	if p.Addr().Is4() {
+		return &t.def4
+	}
+	return &t.def6
+}
+
+// canonical masks p and converts an IPv4-mapped IPv6 prefix to IPv4.
+func canonical(p netip.Prefix) (netip.Prefix, bool) {
+	if !p.IsValid() {
+		return p, false
+	}
+	if a := p.Addr(); a.Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(a.Unmap(), p.Bits()-96)
+	}
+	return p.Masked(), true
+}
+
+func addrToUint32(a netip.Addr) uint32 {
+	b := a.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func addrToUint128(a netip.Addr) Uint128 {
+	b := a.As16()
+	return Uint128{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])}
+}
@@ -0,0 +1,96 @@
+package bitradix
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	tab := NewTable[uint32]()
+	routes := map[string]uint32{
+		"0.0.0.0/0":             1,
+		"10.0.0.0/8":            10,
+		"10.20.0.0/14":          20,
+		"192.168.2.0/24":        1922,
+		"::/0":                  6,
+		"2001:db8::/32":         60,
+		"2001:db8:21:1::1/128":  61,
+		"64:ff9b::/96":          64,
+		"::ffff:172.16.0.0/108": 172,
+	}
+	for p, v := range routes {
+		if err := tab.InsertPrefix(netip.MustParsePrefix(p), v); err != nil {
+			t.Logf("Expected no error for %s, got %s\n", p, err)
+			t.Fail()
+		}
+	}
+	validate(t, tab.v4)
+	validate(t, tab.v6)
+	testips := map[string]uint32{
+		"10.20.1.2":         20,
+		"10.19.0.1":         10,
+		"192.168.2.3":       1922,
+		"230.0.0.1":         1,
+		"::ffff:10.20.1.2":  20,
+		"172.16.1.1":        172,
+		"2001:db8:21:1::1":  61,
+		"2001:db8:21:1::2":  60,
+		"64:ff9b::10.0.0.1": 64,
+		"3ffe::1":           6,
+	}
+	for ip, v := range testips {
+		if x, ok := tab.LookupAddr(netip.MustParseAddr(ip)); !ok || x != v {
+			t.Logf("Expected %d, got %d (%v) for %s\n", v, x, ok, ip)
+			t.Fail()
+		}
+	}
+
+	for _, p := range []netip.Prefix{{}, netip.PrefixFrom(netip.MustParseAddr("10.0.0.0"), 33)} {
+		if err := tab.InsertPrefix(p, 1); err != ErrInvalidPrefixLen {
+			t.Logf("Expected ErrInvalidPrefixLen for %s, got %v\n", p, err)
+			t.Fail()
+		}
+	}
+	validate(t, tab.v4)
+	validate(t, tab.v6)
+
+	if x, ok := tab.Get(netip.MustParsePrefix("10.20.0.0/14")); !ok || x != 20 {
+		t.Logf("Expected %d, got %d (%v)\n", 20, x, ok)
+		t.Fail()
+	}
+	if x, ok := tab.Get(netip.MustParsePrefix("172.16.0.0/12")); !ok || x != 172 {
+		t.Logf("Expected %d, got %d (%v)\n", 172, x, ok)
+		t.Fail()
+	}
+	if _, ok := tab.Get(netip.MustParsePrefix("10.20.0.0/16")); ok {
+		t.Logf("Expected no exact match for 10.20.0.0/16\n")
+		t.Fail()
+	}
+
+	for _, p := range []string{"10.20.0.0/14", "0.0.0.0/0", "2001:db8:21:1::1/128"} {
+		if x, ok := tab.RemovePrefix(netip.MustParsePrefix(p)); !ok || x != routes[p] {
+			t.Logf("Expected %d, got %d (%v) for removal of %s\n", routes[p], x, ok, p)
+			t.Fail()
+		}
+	}
+	validate(t, tab.v4)
+	validate(t, tab.v6)
+	if _, ok := tab.RemovePrefix(netip.MustParsePrefix("10.20.0.0/14")); ok {
+		t.Logf("Expected second removal of 10.20.0.0/14 to fail\n")
+		t.Fail()
+	}
+	testips = map[string]uint32{
+		"10.20.1.2":        10,
+		"2001:db8:21:1::1": 60,
+	}
+	for ip, v := range testips {
+		if x, ok := tab.LookupAddr(netip.MustParseAddr(ip)); !ok || x != v {
+			t.Logf("Expected %d, got %d (%v) for %s\n", v, x, ok, ip)
+			t.Fail()
+		}
+	}
+	if _, ok := tab.LookupAddr(netip.MustParseAddr("230.0.0.1")); ok {
+		t.Logf("Expected no match for 230.0.0.1 after removing the default route\n")
+		t.Fail()
+	}
+}
@@ -0,0 +1,207 @@
+package bitradix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Prefixes are written in the text and JSON formats as IPv4 prefixes for 32 bits
+// keys, as IPv6 prefixes for 64 and 128 bits keys, where a 64 bits key is the upper
+// half of the address, and as a hexadecimal number with a length for other keys.
+
+// ErrPrefix is returned when parsing a prefix that does not fit the key type of the tree.
+var ErrPrefix = errors.New("bitradix: invalid prefix")
+
+// MarshalText implements encoding.TextMarshaler. It returns a line per prefix in
+// the order of All, with the prefix and the value separated by a tab. Values
+// implementing encoding.TextMarshaler use that method, other values are
+// formatted with fmt.Sprint.
+func (r *Radix[K, V]) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for p, v := range r.All() {
+		s, err := formatValue(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(formatPrefix(p.Key, p.Bits))
+		buf.WriteByte('\t')
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The contents of r are
+// replaced by the prefixes in text, which is read like ReadText does with a
+// parse function that uses encoding.TextUnmarshaler when *V implements it, and
+// fmt.Sscan otherwise. Values of a string or interface{} type are used as is.
+// r must be the root of a tree.
+func (r *Radix[K, V]) UnmarshalText(text []byte) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	x := New[K, V]()
+	if err := x.ReadText(bytes.NewReader(text), parseValue[V]); err != nil {
+		return err
+	}
+	r.adopt(x)
+	return nil
+}
+
+// ReadText inserts the prefixes read from rd in the tree r. Each line holds a
+// prefix and a value separated by a tab, the value is converted with parse.
+// Empty lines and lines starting with # are skipped. The error returned tells
+// which line could not be read or inserted. r must be the root of the tree.
+func (r *Radix[K, V]) ReadText(rd io.Reader, parse func(s string) (V, error)) error {
+	s := bufio.NewScanner(rd)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		prefix, value, ok := strings.Cut(text, "\t")
+		if !ok {
+			return fmt.Errorf("bitradix: line %d: no tab after the prefix", line)
+		}
+		n, bits, err := parsePrefix[K](prefix)
+		if err != nil {
+			return fmt.Errorf("bitradix: line %d: %w", line, err)
+		}
+		v, err := parse(value)
+		if err != nil {
+			return fmt.Errorf("bitradix: line %d: %w", line, err)
+		}
+		if _, err := r.TryInsert(n, bits, v); err != nil {
+			return fmt.Errorf("bitradix: line %d: %w", line, err)
+		}
+	}
+	return s.Err()
+}
+
+// jsonPrefix is a prefix and its value in the JSON format.
+type jsonPrefix[V any] struct {
+	Prefix string `json:"prefix"`
+	Value  V      `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler. The tree is written as an array of
+// {"prefix": "10.0.0.0/8", "value": ...} objects in the order of All.
+func (r *Radix[K, V]) MarshalJSON() ([]byte, error) {
+	x := []jsonPrefix[V]{}
+	for p, v := range r.All() {
+		x = append(x, jsonPrefix[V]{formatPrefix(p.Key, p.Bits), v})
+	}
+	return json.Marshal(x)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it reads what MarshalJSON writes. The
+// contents of r are replaced, r must be the root of a tree.
+func (r *Radix[K, V]) UnmarshalJSON(data []byte) error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	var prefixes []jsonPrefix[V]
+	if err := json.Unmarshal(data, &prefixes); err != nil {
+		return err
+	}
+	x := New[K, V]()
+	for _, p := range prefixes {
+		n, bits, err := parsePrefix[K](p.Prefix)
+		if err != nil {
+			return err
+		}
+		if _, err := x.TryInsert(n, bits, p.Value); err != nil {
+			return fmt.Errorf("%w: %s", err, p.Prefix)
+		}
+	}
+	r.adopt(x)
+	return nil
+}
+
+// formatPrefix returns the text representation of n/bits.
+func formatPrefix[K Key](n K, bits int) string {
+	hi, lo := words(n)
+	var b [16]byte
+	switch bitSize[K]() {
+	case 32:
+		binary.BigEndian.PutUint32(b[:4], uint32(lo))
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(b[:4])), bits).String()
+	case 64:
+		binary.BigEndian.PutUint64(b[:8], lo)
+		return netip.PrefixFrom(netip.AddrFrom16(b), bits).String()
+	case 128:
+		binary.BigEndian.PutUint64(b[:8], hi)
+		binary.BigEndian.PutUint64(b[8:], lo)
+		return netip.PrefixFrom(netip.AddrFrom16(b), bits).String()
+	}
+	return fmt.Sprintf("0x%0*x/%d", bitSize[K]()/4, lo, bits)
+}
+
+// parsePrefix is the inverse of formatPrefix. The prefix length and host bits
+// are not checked, except for 64 bits keys, where the lower half of the address
+// must be zero.
+func parsePrefix[K Key](s string) (n K, bits int, err error) {
+	switch bitSize[K]() {
+	case 32, 64, 128:
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return n, 0, fmt.Errorf("%w: %s", ErrPrefix, s)
+		}
+		if p.Addr().Is4() != (bitSize[K]() == 32) {
+			return n, 0, fmt.Errorf("%w: wrong address family: %s", ErrPrefix, s)
+		}
+		if p.Addr().Is4() {
+			b := p.Addr().As4()
+			return fromWords[K](0, uint64(binary.BigEndian.Uint32(b[:]))), p.Bits(), nil
+		}
+		b := p.Addr().As16()
+		hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+		if bitSize[K]() == 64 {
+			if lo != 0 {
+				return n, 0, fmt.Errorf("%w: %s", ErrHostBitsSet, s)
+			}
+			return fromWords[K](0, hi), p.Bits(), nil
+		}
+		return fromWords[K](hi, lo), p.Bits(), nil
+	}
+	key, length, ok := strings.Cut(s, "/")
+	k, err1 := strconv.ParseUint(key, 0, bitSize[K]())
+	bits, err2 := strconv.Atoi(length)
+	if !ok || err1 != nil || err2 != nil {
+		return n, 0, fmt.Errorf("%w: %s", ErrPrefix, s)
+	}
+	return fromWords[K](0, k), bits, nil
+}
+
+// formatValue returns the text representation of v.
+func formatValue[V any](v V) (string, error) {
+	if m, ok := any(v).(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// parseValue is the inverse of formatValue.
+func parseValue[V any](s string) (v V, err error) {
+	switch p := any(&v).(type) {
+	case encoding.TextUnmarshaler:
+		err = p.UnmarshalText([]byte(s))
+	case *string:
+		*p = s
+	case *interface{}:
+		*p = s
+	default:
+		_, err = fmt.Sscan(s, &v)
+	}
+	return v, err
+}
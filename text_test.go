@@ -0,0 +1,117 @@
+package bitradix
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMarshalText(t *testing.T) {
+	r := newIterTree(t)
+	text, err := r.MarshalText()
+	if err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	expected := "8.8.8.0/24\t15169\n10.0.0.0/8\t10\n10.20.0.0/14\t20\n10.21.0.0/16\t21\n192.168.0.0/16\t192\n192.168.2.0/24\t1922\n"
+	if string(text) != expected {
+		t.Logf("Expected\n%s, got\n%s\n", expected, text)
+		t.Fail()
+	}
+
+	r1 := New32Of[uint32]()
+	if err := r1.UnmarshalText(text); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	validate(t, r1)
+	if x := r1.Find(0x0a150101, 32); x == nil || x.Value != 21 {
+		t.Logf("Expected 21, got %v\n", x)
+		t.Fail()
+	}
+	text1, _ := r1.MarshalText()
+	if string(text1) != expected {
+		t.Logf("Expected\n%s, got\n%s\n", expected, text1)
+		t.Fail()
+	}
+}
+
+func TestReadText(t *testing.T) {
+	r := New64Of[string]()
+	input := "# documentation\n2001:db8::/32\tdoc\n\n2a00:1450:4001:800::/56\tgoogle\twith a tab\r\n"
+	if err := r.ReadText(strings.NewReader(input), func(s string) (string, error) { return s, nil }); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	validate(t, r)
+	if x := r.Get(0x2a00145040010800, 56); x == nil || x.Value != "google\twith a tab" {
+		t.Logf("Expected %q, got %v\n", "google\twith a tab", x)
+		t.Fail()
+	}
+
+	tests := map[string]error{
+		"2001:db8::1/32\tx":    ErrHostBitsSet,
+		"2001:db8::/72\tx":     ErrInvalidPrefixLen,
+		"2001:db8::1:0/112\tx": ErrHostBitsSet,
+		"10.0.0.0/8\tx":        ErrPrefix,
+		"2001:db8::/33":        nil,
+	}
+	for line, expected := range tests {
+		err := r.ReadText(strings.NewReader(line), func(s string) (string, error) { return s, nil })
+		if err == nil || (expected != nil && !errors.Is(err, expected)) {
+			t.Logf("Expected %v for %q, got %v\n", expected, line, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	r := New32Of[int]()
+	if data, _ := json.Marshal(r); string(data) != "[]" {
+		t.Logf("Expected [], got %s\n", data)
+		t.Fail()
+	}
+	r.Insert(0x0a000000, 8, 10)
+	validate(t, r)
+	r.Insert(0x08080800, 24, 15169)
+	validate(t, r)
+	data, err := json.Marshal(r)
+	expected := `[{"prefix":"8.8.8.0/24","value":15169},{"prefix":"10.0.0.0/8","value":10}]`
+	if err != nil || string(data) != expected {
+		t.Logf("Expected %s, got %s (%v)\n", expected, data, err)
+		t.Fail()
+	}
+	r1 := New32Of[int]()
+	if err := json.Unmarshal(data, r1); err != nil {
+		t.Logf("Expected no error, got %s\n", err)
+		t.Fail()
+	}
+	validate(t, r1)
+	if data1, _ := json.Marshal(r1); string(data1) != expected {
+		t.Logf("Expected %s, got %s\n", expected, data1)
+		t.Fail()
+	}
+
+	r2 := New128Of[string]()
+	r2.Insert(Uint128{0x2001_0db8_0000_0000, 0}, 32, "doc")
+	validate(t, r2)
+	expected = `[{"prefix":"2001:db8::/32","value":"doc"}]`
+	if data, _ := json.Marshal(r2); string(data) != expected {
+		t.Logf("Expected %s, got %s\n", expected, data)
+		t.Fail()
+	}
+
+	r3 := New[uint16, int]()
+	r3.Insert(0x0a00, 8, 1)
+	validate(t, r3)
+	expected = `[{"prefix":"0x0a00/8","value":1}]`
+	if data, _ := json.Marshal(r3); string(data) != expected {
+		t.Logf("Expected %s, got %s\n", expected, data)
+		t.Fail()
+	}
+	if err := json.Unmarshal([]byte(expected), r3); err != nil || r3.Get(0x0a00, 8) == nil {
+		t.Logf("Expected 0x0a00/8, got %v\n", err)
+		t.Fail()
+	}
+}
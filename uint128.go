@@ -0,0 +1,20 @@
+package bitradix
+
+// Uint128 is a 128 bits unsigned integer, it is used as the key in Radix128.
+type Uint128 struct {
+	Hi uint64 // the upper (most significant) 64 bits
+	Lo uint64 // the lower 64 bits
+}
+
+// mask128 returns a 128 bits mask with the first bits bits set.
+func mask128(bits int) (hi, lo uint64) {
+	switch {
+	case bits <= 0:
+		return 0, 0
+	case bits <= 64:
+		return ^uint64(0) << uint(64-bits), 0
+	case bits < 128:
+		return ^uint64(0), ^uint64(0) << uint(128-bits)
+	}
+	return ^uint64(0), ^uint64(0)
+}
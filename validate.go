@@ -0,0 +1,86 @@
+package bitradix
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTree is wrapped by the errors returned by Validate.
+var ErrInvalidTree = errors.New("bitradix: invalid tree")
+
+// Validate checks the structure of the tree r and returns an error wrapping
+// ErrInvalidTree describing the first problem found, or nil when the tree is
+// valid. It checks that every node is the parent of its branches, that a node
+// covers a longer prefix than its parent and sits in the branch of its parent
+// the bit after the parent's prefix selects, that a key has as many bits as the
+// prefix its node covers and that a node without a key has two branches. r must
+// be the root of the tree.
+func (r *Radix[K, V]) Validate() error {
+	if r.parent != nil {
+		return ErrNotRoot
+	}
+	if r.skip != 0 || r.bits != 0 {
+		return fmt.Errorf("%w: root covers %d bits and holds %d bits", ErrInvalidTree, r.skip, r.bits)
+	}
+	return r.validate()
+}
+
+func (r *Radix[K, V]) validate() error {
+	for i, b := range r.branch {
+		if b == nil {
+			continue
+		}
+		if b.parent != r {
+			return fmt.Errorf("%w: %s does not have %s as parent", ErrInvalidTree, formatPrefix(b.key, b.skip), formatPrefix(r.key, r.skip))
+		}
+		if err := validateNode(r.key, r.skip, i, b.key, b.skip, b.bits, b.branch[0] != nil && b.branch[1] != nil); err != nil {
+			return err
+		}
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate is like Radix.Validate, there are no parent pointers to check.
+func (r *Immutable[K, V]) Validate() error {
+	if r.skip != 0 || r.bits != 0 {
+		return fmt.Errorf("%w: root covers %d bits and holds %d bits", ErrInvalidTree, r.skip, r.bits)
+	}
+	return r.validate()
+}
+
+func (r *Immutable[K, V]) validate() error {
+	for i, b := range r.branch {
+		if b == nil {
+			continue
+		}
+		if err := validateNode(r.key, r.skip, i, b.key, b.skip, b.bits, b.branch[0] != nil && b.branch[1] != nil); err != nil {
+			return err
+		}
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNode checks a node covering key/skip, holding bits bits, in branch i of
+// a node covering pkey/pskip.
+func validateNode[K Key](pkey K, pskip, i int, key K, skip, bits int, both bool) error {
+	p := formatPrefix(key, skip)
+	switch {
+	case skip <= pskip || skip > bitSize[K]():
+		return fmt.Errorf("%w: %s is not longer than its parent %s", ErrInvalidTree, p, formatPrefix(pkey, pskip))
+	case hostBits(key, skip):
+		return fmt.Errorf("%w: %s has bits set beyond its prefix", ErrInvalidTree, p)
+	case !match(key, pkey, pskip) || int(bitK(key, bitSize[K]()-1-pskip)) != i:
+		return fmt.Errorf("%w: %s is not in branch %d of %s", ErrInvalidTree, p, i, formatPrefix(pkey, pskip))
+	case bits != 0 && bits != skip:
+		return fmt.Errorf("%w: %s holds a key of %d bits", ErrInvalidTree, p, bits)
+	case bits == 0 && !both:
+		return fmt.Errorf("%w: %s holds no key and does not have two branches", ErrInvalidTree, p)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package bitradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]func(r *Radix32){
+		"parent":       func(r *Radix32) { r.Get(0x0A150000, 16).parent = r },
+		"skip":         func(r *Radix32) { r.Get(0x0A150000, 16).skip = 12 },
+		"bits":         func(r *Radix32) { r.Get(0x0A150000, 16).bits = 15 },
+		"host bits":    func(r *Radix32) { r.Get(0x0A150000, 16).key |= 1 },
+		"subtree":      func(r *Radix32) { x := r.Get(0x0A000000, 8); x.branch[0], x.branch[1] = x.branch[1], x.branch[0] },
+		"empty leaf":   func(r *Radix32) { r.Get(0x08080800, 24).clear() },
+		"empty single": func(r *Radix32) { r.Get(0x0A000000, 8).clear() },
+		"root":         func(r *Radix32) { r.bits = 1 },
+	}
+	for name, corrupt := range tests {
+		r := newIterTree(t)
+		corrupt(r)
+		err := r.Validate()
+		t.Logf("%s: %v\n", name, err)
+		if !errors.Is(err, ErrInvalidTree) {
+			t.Logf("Expected %v for %s, got %v\n", ErrInvalidTree, name, err)
+			t.Fail()
+		}
+	}
+	if err := newIterTree(t).Get(0x0A000000, 8).Validate(); err != ErrNotRoot {
+		t.Logf("Expected %v, got %v\n", ErrNotRoot, err)
+		t.Fail()
+	}
+}